@@ -0,0 +1,73 @@
+//go:build go1.19
+
+package diff_test
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+type counters struct {
+	N    atomic.Int64
+	On   atomic.Bool
+	Name atomic.Pointer[string]
+	V    atomic.Value
+}
+
+func newCounters(n int64, on bool, name string, v any) *counters {
+	c := new(counters)
+	c.N.Store(n)
+	c.On.Store(on)
+	c.Name.Store(&name)
+	if v != nil {
+		c.V.Store(v)
+	}
+	return c
+}
+
+func TestAtomic(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b *counters
+		want string
+	}{
+		{"equal", newCounters(1, true, "x", 1), newCounters(1, true, "x", 1), ""},
+		{"int", newCounters(1, true, "x", nil), newCounters(2, true, "x", nil),
+			"diff_test.counters.N: 1 != 2\n"},
+		{"bool", newCounters(1, true, "x", nil), newCounters(1, false, "x", nil),
+			"diff_test.counters.On: true != false\n"},
+		{"pointer", newCounters(1, true, "x", nil), newCounters(1, true, "y", nil),
+			"diff_test.counters.Name: \"x\" != \"y\"\n"},
+		{"value", newCounters(1, true, "x", 1), newCounters(1, true, "x", 2),
+			"diff_test.counters.V: int(1) != int(2)\n"},
+		{"value nil", newCounters(1, true, "x", nil), newCounters(1, true, "x", 2),
+			"diff_test.counters.V: nil != int(2)\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func TestAtomicFormat(t *testing.T) {
+	type T struct{ N atomic.Int64 }
+	a := new(T)
+	a.N.Store(5)
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, (*T)(nil))
+	want := "&diff_test.T{N:5} != (*diff_test.T)(nil)\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package diff
+
+import (
+	"encoding"
+	"reflect"
+)
+
+var reflectBinaryMarshaler = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+
+// marshalBinary returns the result of MarshalBinary
+// for v, if v's type or, for an addressable v,
+// a pointer to v implements encoding.BinaryMarshaler.
+func marshalBinary(v reflect.Value) ([]byte, bool) {
+	if !v.Type().Implements(reflectBinaryMarshaler) && v.CanAddr() {
+		v = v.Addr()
+	}
+	if !v.Type().Implements(reflectBinaryMarshaler) || !v.CanInterface() || isNil(v) {
+		return nil, false
+	}
+	b, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+	return b, err == nil
+}
+
+// walkBinary compares av and bv by their binary encodings,
+// for BinaryMarshal. It reports false, having done nothing,
+// if either can't be marshaled.
+func (d *differ) walkBinary(e emitfer, av, bv reflect.Value) bool {
+	d.config.helper()
+	a, ok := marshalBinary(av)
+	if !ok {
+		return false
+	}
+	b, ok := marshalBinary(bv)
+	if !ok {
+		return false
+	}
+	d.tracef(e, "%v compared as binary (BinaryMarshal)", av.Type())
+	d.stringDiff(e, av, bv, string(a), string(b))
+	return true
+}
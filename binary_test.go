@@ -0,0 +1,62 @@
+package diff_test
+
+import (
+	"errors"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+// key has private state and a compact encoding
+// that leaves out its cache.
+type key struct {
+	id    [2]byte
+	cache *string
+}
+
+func (k key) MarshalBinary() ([]byte, error) { return k.id[:], nil }
+
+// ptrKey implements BinaryMarshaler on its pointer type.
+type ptrKey struct{ n byte }
+
+func (k *ptrKey) MarshalBinary() ([]byte, error) { return []byte{k.n}, nil }
+
+// badKey fails to marshal.
+type badKey struct{ n int }
+
+func (badKey) MarshalBinary() ([]byte, error) { return nil, errors.New("no") }
+
+func TestBinaryMarshal(t *testing.T) {
+	s := "cached"
+	type T struct {
+		K key
+		P ptrKey
+		B badKey
+	}
+	cases := []struct {
+		name string
+		a, b T
+		want string
+	}{
+		{"equal", T{K: key{id: [2]byte{1, 2}}}, T{K: key{id: [2]byte{1, 2}, cache: &s}}, ""},
+		{"key", T{K: key{id: [2]byte{1, 2}}}, T{K: key{id: [2]byte{1, 3}}},
+			"diff_test.T.K: \"\\x01\\x02\" != \"\\x01\\x03\"\n"},
+		{"binary", T{K: key{id: [2]byte{0xff, 2}}}, T{K: key{id: [2]byte{0xff, 3}}},
+			"diff_test.T.K: binary: \"\\xff\\x02\" != \"\\xff\\x03\"\n"},
+		{"pointer", T{P: ptrKey{1}}, T{P: ptrKey{2}},
+			"diff_test.T.P: \"\\x01\" != \"\\x02\"\n"},
+		{"error", T{B: badKey{1}}, T{B: badKey{2}},
+			"diff_test.T.B.n: 1 != 2\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, tt.a, tt.b, diff.BinaryMarshal(true))
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package diff
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences for colored output.
+const (
+	colorA     = "\x1b[31m" // red
+	colorB     = "\x1b[32m" // green
+	colorReset = "\x1b[0m"
+)
+
+type colorMode int
+
+const (
+	colorAuto colorMode = iota
+	colorNever
+	colorAlways
+)
+
+// useColor reports whether output should be colored,
+// following the rules described in Color.
+func (c *config) useColor() bool {
+	switch c.color {
+	case colorNever:
+		return false
+	case colorAlways:
+		return true
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if os.Getenv("FORCE_COLOR") != "" {
+		return true
+	}
+	return c.tty
+}
+
+// isTerminal reports whether out is a logger
+// that writes to a terminal.
+func isTerminal(out Outputter) bool {
+	l, ok := out.(*log.Logger)
+	if !ok {
+		return false
+	}
+	f, ok := l.Writer().(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// decorate formats a difference for EmitAuto, prefix followed
+// by format and arg, applying the Width and Color options.
+func (e *printEmitter) decorate(prefix, format string, arg []any) string {
+	w := e.config.width
+	if x, y, ok := splitNE(format, arg); ok && !strings.Contains(x+y, "\n") {
+		if w > 0 {
+			x, y = fitOperands(prefix, x, y, w)
+		}
+		if e.out.color {
+			x, y = colorA+x+colorReset, colorB+y+colorReset
+		}
+		return prefix + x + " != " + y
+	}
+	s := prefix + fmt.Sprintf(format, arg...)
+	if w > 0 {
+		s = fitLines(s, w)
+	}
+	if e.out.color {
+		s = colorLines(s)
+	}
+	return s
+}
+
+// colorLines colors the lines of s that show
+// something removed from a or added in b:
+// the lines of a unified diff starting with - or +,
+// and (removed) and (added) entries.
+func colorLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		t := strings.TrimLeft(line, " \t"+tab)
+		switch {
+		case strings.HasPrefix(t, "---"), strings.HasPrefix(t, "+++"):
+		case strings.HasPrefix(t, "-"), strings.HasSuffix(t, "(removed)"):
+			lines[i] = colorA + line + colorReset
+		case strings.HasPrefix(t, "+"), strings.Contains(t, "(added)"):
+			lines[i] = colorB + line + colorReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
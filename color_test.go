@@ -0,0 +1,48 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestColor(t *testing.T) {
+	type T struct {
+		N int
+		M map[string]int
+	}
+	a := T{1, map[string]int{"x": 1}}
+	b := T{2, map[string]int{"y": 1}}
+	plain := "diff_test.T.N: 1 != 2\n" +
+		"diff_test.T.M[\"x\"]: (removed)\n" +
+		"diff_test.T.M[\"y\"]: (added) 1\n"
+	colored := "diff_test.T.N: \x1b[31m1\x1b[0m != \x1b[32m2\x1b[0m\n" +
+		"\x1b[31mdiff_test.T.M[\"x\"]: (removed)\x1b[0m\n" +
+		"\x1b[32mdiff_test.T.M[\"y\"]: (added) 1\x1b[0m\n"
+	cases := []struct {
+		name    string
+		noColor string
+		force   string
+		opt     []diff.Option
+		want    string
+	}{
+		{"default", "", "", nil, plain},
+		{"on", "", "", []diff.Option{diff.Color(true)}, colored},
+		{"off", "", "1", []diff.Option{diff.Color(false)}, plain},
+		{"FORCE_COLOR", "", "1", nil, colored},
+		{"NO_COLOR", "1", "1", nil, plain},
+		{"override NO_COLOR", "1", "", []diff.Option{diff.Color(true)}, colored},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("FORCE_COLOR", tt.force)
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, a, b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
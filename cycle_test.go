@@ -0,0 +1,131 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+type cnode struct {
+	N    int
+	Next *cnode
+}
+
+func TestCycles(t *testing.T) {
+	ring := func(n int) *cnode {
+		first := &cnode{N: 0}
+		p := first
+		for i := 1; i < n; i++ {
+			p.Next = &cnode{N: 0}
+			p = p.Next
+		}
+		p.Next = first
+		return first
+	}
+	chain := func(n int) *cnode {
+		var p *cnode
+		for i := 0; i < n; i++ {
+			p = &cnode{N: 0, Next: p}
+		}
+		return p
+	}
+	cases := []struct {
+		name string
+		a, b *cnode
+		want string
+	}{
+		{"same", ring(2), ring(2), ""},
+		{"lengths", ring(2), ring(3), "diff_test.cnode.Next.Next: cycle in a but not b\n"},
+		{"a only", ring(1), chain(2), "diff_test.cnode.Next: cycle in a but not b\n"},
+		{"b only", chain(2), ring(1), "diff_test.cnode.Next: cycle in b but not a\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func TestSharedNotCycle(t *testing.T) {
+	type T struct{ X, Y *cnode }
+	shared := &cnode{N: 1}
+	a := T{shared, shared}
+	b := T{&cnode{N: 1}, &cnode{N: 1}}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b)
+	if got != "" {
+		t.Errorf("shared vs copied = %q, want equal", got)
+	}
+
+	diff.Each(gotp.Printf, a, b, diff.Aliasing(true))
+	want := "diff_test.T.Y: aliasing differs: shared with diff_test.T.X in a but not b\n"
+	if got != want {
+		t.Errorf("Aliasing(true) = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, b, a, diff.Aliasing(true))
+	want = "diff_test.T.Y: aliasing differs: shared with diff_test.T.X in b but not a\n"
+	if got != want {
+		t.Errorf("Aliasing(true) reversed = %q, want %q", got, want)
+	}
+}
+
+func TestSharedDifferently(t *testing.T) {
+	type T struct{ W, X, Y *cnode }
+	p, q := &cnode{N: 1}, &cnode{N: 1}
+	r, s := &cnode{N: 1}, &cnode{N: 1}
+	a := T{q, p, p}
+	b := T{s, r, s}
+
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, a, b, diff.Aliasing(true))
+	want := "diff_test.T.Y: aliasing differs: shared with diff_test.T.X in a but with diff_test.T.W in b\n"
+	if got != want {
+		t.Errorf("Aliasing(true) = %q, want %q", got, want)
+	}
+}
+
+type dagNode struct {
+	N           int
+	Left, Right *dagNode
+}
+
+// dag returns a graph with 2^depth paths
+// from the root to the bottom node,
+// but only depth+1 nodes.
+func dag(depth, bottom int) *dagNode {
+	n := &dagNode{N: bottom}
+	for i := 0; i < depth; i++ {
+		n = &dagNode{N: i, Left: n, Right: n}
+	}
+	return n
+}
+
+func TestSharedMemo(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, dag(60, 0), dag(60, 0))
+	if got != "" {
+		t.Errorf("equal DAGs: got %q, want empty", got)
+	}
+
+	a, b := dag(60, 0), dag(60, 0)
+	b.Left = &dagNode{N: -1}
+	diff.Each(gotp.Printf, a, b)
+	want := "diff_test.dagNode.Left.N: 58 != -1\n" +
+		"diff_test.dagNode.Left.Left: {N:57, ...} != nil\n" +
+		"diff_test.dagNode.Left.Right: {N:57, ...} != nil\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -2,20 +2,29 @@ package diff
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"runtime"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 	"unicode/utf8"
 	"unsafe"
 
 	"github.com/rogpeppe/go-internal/fmtsort"
+
+	"kr.dev/diff/internal/indent"
 )
 
 var (
 	reflectBytes  = reflect.TypeOf((*[]byte)(nil)).Elem()
 	reflectString = reflect.TypeOf((*string)(nil)).Elem()
 	reflectBool   = reflect.TypeOf(true)
+
+	reflectTimePtr = reflect.TypeOf((*time.Time)(nil))
 )
 
 var (
@@ -25,6 +34,13 @@ var (
 // Each compares values a and b, calling f for each difference it finds.
 // By default, its conditions for equality are like reflect.DeepEqual.
 //
+// If a or b is a reflect.Value, Each compares the value it holds,
+// not the reflect.Value itself. This works for values
+// obtained through unexported struct fields, as long as
+// they are addressable; otherwise Each panics.
+// The same is true of the other functions in this package
+// that compare two values.
+//
 // The behavior can be adjusted by supplying Option values.
 // See Default for a complete list of default options.
 // Values in opt apply in addition to (and override) the defaults.
@@ -50,7 +66,31 @@ func Log(a, b any, opt ...Option) {
 		d.config.output.Output(dd+2, fmt.Sprintf(format, arg...))
 	}
 	d = newDiffer(func() {}, f, opt...)
+	d.config.tty = isTerminal(d.config.output)
+	d.each(a, b)
+}
+
+// Must compares values a and b, and panics if it finds any
+// differences, with a message listing them, for example:
+//
+//	diff: values differ:
+//	T.Name: "a" != "b"
+//
+// This is for checks that should never fail, such as
+// invariants checked at init time, or in example programs.
+//
+// The behavior can be adjusted by supplying Option values.
+// See Default for a complete list of default options.
+// Values in opt apply in addition to (and override) the defaults.
+func Must(a, b any, opt ...Option) {
+	var buf strings.Builder
+	d := newDiffer(func() {}, func(format string, arg ...any) {
+		fmt.Fprintf(&buf, format, arg...)
+	}, opt...)
 	d.each(a, b)
+	if buf.Len() > 0 {
+		panic("diff: values differ:\n" + strings.TrimSuffix(buf.String(), "\n"))
+	}
 }
 
 // Test compares values got and want, calling f for each difference it finds.
@@ -74,42 +114,325 @@ func Test(h Helperer, f func(format string, arg ...any), got, want any, opt ...O
 	d.each(got, want)
 }
 
+// AtCleanup is like Test, but it compares the values
+// when the test finishes, using t.Cleanup, with got
+// called then to get the value to check. This lets
+// a check of the final state of something be declared
+// near the setup it verifies, for example:
+//
+//	db := newTestDB(t)
+//	diff.AtCleanup(t, db.Rows, wantRows)
+//
+// Differences are reported with t.Errorf.
+func AtCleanup(t TB, got func() any, want any, opt ...Option) {
+	t.Helper()
+	t.Cleanup(func() {
+		t.Helper()
+		Test(t, t.Errorf, got(), want, opt...)
+	})
+}
+
+// EachT is like Each, but a and b must have the same static type.
+// Comparing values of different types is a compile-time error
+// rather than a reported difference.
+func EachT[T any](f func(format string, arg ...any) (int, error), a, b T, opt ...Option) {
+	Each(f, a, b, opt...)
+}
+
+// TestT is like Test, but got and want must have the same static type.
+// Comparing values of different types is a compile-time error
+// rather than a reported difference.
+func TestT[T any](h Helperer, f func(format string, arg ...any), got, want T, opt ...Option) {
+	h.Helper()
+	Test(h, f, got, want, opt...)
+}
+
+// TestCases compares the results of a table-driven test,
+// got and want, case by case, like TestT, but labels the
+// differences in each case with the case's name, as
+// returned by name for its index, rather than its
+// index in the slice, for example:
+//
+//	case "empty input": pkg.Result.N: 0 != 1
+//
+// If got and want differ in length, TestCases reports that,
+// then compares the cases they have in common.
+func TestCases[E any](h Helperer, f func(format string, arg ...any), got, want []E, name func(i int) string, opt ...Option) {
+	h.Helper()
+	if len(got) != len(want) {
+		f("got %d cases, want %d\n", len(got), len(want))
+	}
+	for i := 0; i < len(got) && i < len(want); i++ {
+		label := Prefix(fmt.Sprintf("case %q: ", name(i)))
+		TestT(h, f, got[i], want[i], append(opt[:len(opt):len(opt)], label)...)
+	}
+}
+
 // Helperer marks the caller as a helper function.
 // It is satisfied by *testing.T and *testing.B.
 type Helperer interface {
 	Helper()
 }
 
+// TB is the part of testing.TB used by AtCleanup.
+// It is satisfied by *testing.T and *testing.B.
+type TB interface {
+	Helperer
+	Cleanup(f func())
+	Errorf(format string, arg ...any)
+}
+
 type differ struct {
 	config config
-	aSeen  map[visit]visit
-	bSeen  map[visit]visit
+
+	// aSeen and bSeen hold the pointers on the path
+	// from the root to the current value, for finding cycles.
+	aSeen map[visit]visit
+	bSeen map[visit]visit
+
+	// memo records pairs of pointers reached so far
+	// and whether they are known to be equal.
+	memo map[[2]visit]memoState
+
+	// path is the location of the current value,
+	// kept only if some options apply beneath a path.
+	// See At.
+	path []step
+
+	// root is the type of the values being compared,
+	// for paths given to the Display hook.
+	root reflect.Type
+
+	// aShared and bShared hold every pointer seen so far,
+	// for Aliasing.
+	aShared map[visit]alias
+	bShared map[visit]alias
+
+	// stop ends the walk early.
+	// Once it is set, walk returns without doing anything.
+	stop bool
+
+	// deadline, if set, is when to stop the walk, for Timeout.
+	// Walk checks it every deadlineEvery visits, counted in nvisit.
+	deadline time.Time
+	nvisit   int
+	timedOut bool
+
+	// ctx, if set, ends the walk when it is done, for Stream.
+	// Walk checks it along with deadline.
+	ctx context.Context
 }
 
+const deadlineEvery = 256
+
 type config struct {
 	sink func(format string, a ...any)
 
+	// change, if set, receives each difference
+	// in place of sink.
+	change func(Change)
+
 	level level // verbosity
 
+	// fullValues shows the full form of both values
+	// after each difference, for EmitAuto.
+	fullValues bool
+
+	template *template.Template // replaces level, if set
+
+	// mapSets compares maps with bool or empty struct
+	// values as sets of keys.
+	mapSets bool
+
+	// normKeys holds funcs that normalize map keys,
+	// by key type, for NormalizeKeys.
+	normKeys map[reflect.Type]reflect.Value
+
+	// pointerKeys matches the entries of maps keyed
+	// by pointers by the values the keys point to.
+	pointerKeys bool
+
 	// equalFuncs treats non-nil functions as equal.
 	// In the == operator, non-nil function values
 	// are never equal, so it is often useless to compare them.
 	equalFuncs bool
 
+	// equalTypedNil treats a nil value of any type
+	// held in an interface as equal to a nil interface.
+	equalTypedNil bool
+
+	// errorChains compares errors by their chains
+	// of types and messages.
+	errorChains bool
+
+	// headers compares http.Header and textproto.MIMEHeader
+	// values as headers, and ignoreHeaders holds the
+	// canonical names of fields to leave out.
+	headers       bool
+	ignoreHeaders map[string]bool
+
+	// queryValues compares url.Values, and the query
+	// in a url.URL, as multisets of values for each key.
+	queryValues bool
+
+	// equalLocks treats values of the sync package's
+	// lock types as equal.
+	equalLocks bool
+
+	// ignoreZeroTime treats a zero time.Time, or a nil
+	// *time.Time, as equal to any other time.
+	ignoreZeroTime bool
+
+	// durationTolerance treats time.Duration values
+	// within this distance of each other as equal.
+	durationTolerance time.Duration
+
+	// binaryMarshal compares values that implement
+	// encoding.BinaryMarshaler by their encodings.
+	binaryMarshal bool
+
+	// vector compares slices and arrays of floats
+	// by an aggregate metric, if set.
+	vector *vectorTol
+
+	// uintptrHandles compares uintptr values
+	// only by whether they are zero.
+	uintptrHandles bool
+
+	// chanMetadata compares channels by capacity
+	// and length rather than identity.
+	chanMetadata bool
+
+	// emitEqual notes each equal value at the bottom
+	// of the structure, as well as the differences.
+	emitEqual bool
+
+	// sortFields walks struct fields in order
+	// by name rather than declaration order.
+	sortFields bool
+
+	failFast bool // stop at the first difference
+
+	aliasing bool // report differences in pointer sharing
+
+	timeout time.Duration // stop the walk after this long, if nonzero
+
 	// xform transforms values of the given type before
 	// they are included in the diff tree.
 	// hashes, weights, and differences are computed
 	// using the transformed values.
-	xform map[reflect.Type]reflect.Value
+	// Each type has a chain of funcs, applied in order.
+	xform map[reflect.Type][]transform
 
 	format map[reflect.Type]reflect.Value
 
+	// funcs caches lookups in xform and format.
+	funcs *funcCache
+
+	// kindEq holds functions that report whether two
+	// values of the given kind are equal, for CompareKind.
+	kindEq map[reflect.Kind]func(a, b reflect.Value) bool
+
+	// unordered holds slice types whose elements
+	// are paired up regardless of their order.
+	unordered map[reflect.Type]bool
+
+	// sliceSets holds slice types compared
+	// as sets of unique elements.
+	sliceSets map[reflect.Type]bool
+
+	// collapse is the minimum length of a run of equal
+	// list elements to summarize in the output.
+	// Zero means don't summarize.
+	collapse int
+
+	// elemContext is the number of equal list elements
+	// to show on either side of each differing element.
+	elemContext int
+
+	// aggregate is the minimum number of list elements
+	// with an identical difference to summarize as one.
+	// Zero means don't summarize.
+	aggregate int
+
+	// containerSummary notes the number of elements added,
+	// removed, and changed in a list or map with differences.
+	containerSummary bool
+
+	// similarity notes the percentage of elements that are
+	// equal in a list or map with differences.
+	similarity bool
+
+	// maxDiffs and maxBytes limit the amount of output.
+	// Zero means no limit.
+	maxDiffs int
+	maxBytes int
+
+	// maxContainerDiffs limits the number of values
+	// with differences shown inside any one value.
+	// Zero means no limit.
+	maxContainerDiffs int
+
+	color colorMode // see Color
+	tty   bool      // output is a terminal, for Color
+
+	display display // see Redact
+
+	// scopes hold options that apply only
+	// beneath a path. See At.
+	scopes []scope
+
+	// pathScoped means At was used, so comparisons
+	// must keep track of the path to each value.
+	pathScoped bool
+
+	// width is the length to shorten lines
+	// of output to. Zero means no limit.
+	width int
+
+	// pointerIDs numbers the pointers in full values.
+	pointerIDs bool
+
+	// fullWidth is the length to cut lines of
+	// full values at, or 0 for no limit.
+	fullWidth int
+
+	// textContext is the number of unchanged lines
+	// shown around each hunk of a multi-line text diff.
+	textContext int
+
+	// normalize holds functions applied, in order,
+	// to strings before comparing them.
+	normalize []func(string) string
+
+	crlf bool // treat \r\n as \n in strings
+
 	helper func()
 	output Outputter
 
 	inTest bool
 	aLabel string
 	bLabel string
+	prefix string // written at the start of each line of output
+
+	location location // see Location
+
+	pathStyle pathStyle
+
+	trace io.Writer // see Trace
+}
+
+// A location is a place in a source file, for Location.
+type location struct {
+	file string
+	line int
+}
+
+func (l location) String() string {
+	if l.file == "" {
+		return "none"
+	}
+	return fmt.Sprintf("%s:%d", l.file, l.line)
 }
 
 type visit struct {
@@ -119,30 +442,136 @@ type visit struct {
 
 type emitfer interface {
 	emitf(av, bv reflect.Value, format string, arg ...any)
-	subf(t reflect.Type, format string, arg ...any) emitfer
+	sub(t reflect.Type, s step) emitfer
 	didEmit() bool
+
+	// steps returns the path from the root
+	// to the current location.
+	steps() []step
+
+	// notef describes the values at this path without
+	// reporting a difference. It does not affect didEmit.
+	notef(format string, arg ...any)
 }
 
 type printEmitter struct {
-	config   config // not pointer, printEmitters have different configs
+	config   *config // shared by all sub-emitters
+	root     reflect.Type
 	rootType string
-	path     []string
+	step     step   // the last step of the path, if depth > 0
+	depth    int    // the length of the path
+	path     []step // built by steps, from step and parent
+	parent   *printEmitter
 	did      bool
+	out      *output // shared by all sub-emitters
+
+	// For MaxContainerDiffs: the number of values inside
+	// this one with differences shown and left out,
+	// and whether this value's differences are left out.
+	nshown  int
+	nhidden int
+	hidden  bool
+
+	noted bool // for EmitEqual
+}
+
+// An output holds the state of the output of
+// a tree of printEmitters.
+type output struct {
+	table   *table     // for EmitColumns
+	tree    *treeNode  // for EmitTree
+	sides   *sideTable // for EmitSideBySide
+	ndiff   int        // differences written
+	nbyte   int        // bytes written
+	dropped int        // differences not written, due to limits
+
+	stop *bool // set to end the walk, for FailFast
+
+	timedOut time.Duration // the Timeout, if it was exceeded
+
+	color bool // see Color
+
+	ptrIDs pointerIDs // see PointerIDs
+
+	sarif *sarifOutput // for EmitSARIF
+
+	tapStarted bool // the TAP version line is written
+}
+
+// markDiff records that a difference was found
+// at the current location.
+func (e *printEmitter) markDiff() {
+	for p := e; p != nil; p = p.parent {
+		p.did = true
+	}
+	if e.config.failFast {
+		*e.out.stop = true
+	}
 }
 
 func (e *printEmitter) emitf(av, bv reflect.Value, format string, arg ...any) {
 	e.config.helper()
-	e.did = true
+	hide := e.hide()
+	e.markDiff()
+	if e.config.change != nil {
+		e.config.change(Change{
+			Path: e.pathValue(),
+			A:    valueInterface(av),
+			B:    valueInterface(bv),
+			Text: fmt.Sprintf(format, arg...),
+		})
+		return
+	}
+	if hide {
+		return
+	}
+	if n := e.config.maxDiffs; n > 0 && e.out.ndiff >= n {
+		e.out.dropped++
+		return
+	}
+	e.out.ndiff++
+	if e.config.display.hook != nil {
+		locate(arg, e.pathValue())
+	}
+	if e.config.template != nil {
+		e.writeTemplate(av, bv, format, arg...)
+		return
+	}
 	switch e.config.level {
 	case auto:
 		var p string
-		if len(e.path) > 0 {
-			p = strings.Join(e.path, "") + ": "
+		if e.depth > 0 {
+			p = e.pathString() + ": "
+		}
+		if e.config.width > 0 || e.out.color {
+			format, arg = "%s", []any{e.decorate(p, format, arg)}
+			p = ""
+		}
+		format, arg = "%s"+format+"\n", append([]any{p}, arg...)
+		if e.config.fullValues {
+			format, arg = e.appendFull(format, arg, av, bv)
+		}
+		e.write(true, format, arg...)
+	case columns:
+		if x, y, ok := splitNE(format, arg); ok {
+			e.out.table.add(e.pathCell(), x, "!= "+y)
+		} else {
+			e.out.table.add(e.pathCell(), fmt.Sprintf(format, arg...))
 		}
-		arg = append([]any{e.rootType, p}, arg...)
-		e.config.sink("%s%s"+format+"\n", arg...)
+	case tree:
+		e.out.tree.add(e.treeLabels(), fmt.Sprintf(format, arg...))
+	case sideBySide, dotGraph:
+		e.out.sides.diffs = append(e.out.sides.diffs, e.pathString())
+	case jsonLines:
+		e.writeJSONLine(av, bv, format, arg...)
+	case sarif:
+		e.addSARIF(av, bv, format, arg...)
+	case githubActions:
+		e.writeGitHub(fmt.Sprintf(format, arg...))
+	case tap:
+		e.writeTAP(av, bv, format, arg...)
 	case pathOnly:
-		e.config.sink("%s%s\n", e.rootType, strings.Join(e.path, ""))
+		e.write(true, "%s\n", e.pathString())
 	case full:
 		var t string
 		if e.rootType != "" {
@@ -150,108 +579,506 @@ func (e *printEmitter) emitf(av, bv reflect.Value, format string, arg ...any) {
 		} else if e.config.inTest {
 			t = "any:\n"
 		}
-		p := strings.Join(e.path, "")
-		e.config.sink("%s%s%s:\n%#v\n%s%s:\n%#v\n", t,
-			e.config.aLabel, p, formatFull(av),
-			e.config.bLabel, p, formatFull(bv),
+		p := formatPath(e.config.pathStyle, e.steps())
+		e.write(true, "%s%s%s:\n%#v\n%s%s:\n%#v\n", t,
+			e.config.aLabel, p, e.formatFull(av),
+			e.config.bLabel, p, e.formatFull(bv),
 		)
 	default:
 		panic("diff: bad verbose level")
 	}
 }
 
-func (e *printEmitter) subf(t reflect.Type, format string, arg ...any) emitfer {
-	if e.rootType == "" {
+func (e *printEmitter) notef(format string, arg ...any) {
+	e.config.helper()
+	if e.inHidden() {
+		return
+	}
+	switch e.config.level {
+	case auto:
+	case columns:
+		e.out.table.add(e.pathCell(), fmt.Sprintf(format, arg...))
+		return
+	case tree:
+		e.out.tree.add(e.treeLabels(), fmt.Sprintf(format, arg...))
+		return
+	default:
+		return
+	}
+	var p string
+	if e.depth > 0 {
+		p = e.pathString() + ": "
+	}
+	arg = append([]any{p}, arg...)
+	e.write(false, "%s"+format+"\n", arg...)
+}
+
+// hide reports whether a difference at e is left out
+// because a value it is inside already has its limit
+// of values with differences, set by MaxContainerDiffs.
+// It must be called before e is marked as having emitted.
+func (e *printEmitter) hide() bool {
+	n := e.config.maxContainerDiffs
+	if n <= 0 || e.config.level == sideBySide || e.config.level == dotGraph {
+		return false
+	}
+	if e.inHidden() {
+		return true
+	}
+	for c := e; c.parent != nil && !c.did; c = c.parent {
+		p := c.parent
+		if p.nshown >= n {
+			c.hidden = true
+			p.nhidden++
+			return true
+		}
+		p.nshown++
+	}
+	return false
+}
+
+// inHidden reports whether e or a value it is inside
+// has its differences left out by MaxContainerDiffs.
+func (e *printEmitter) inHidden() bool {
+	for p := e; p != nil; p = p.parent {
+		if p.hidden {
+			return true
+		}
+	}
+	return false
+}
+
+// appendFull appends the full form of av and bv,
+// whichever are valid, to format and arg.
+func (e *printEmitter) appendFull(format string, arg []any, av, bv reflect.Value) (string, []any) {
+	if av.IsValid() {
+		format += "%s:\n%#v\n"
+		arg = append(arg, e.config.aLabel, e.formatFull(av))
+	}
+	if bv.IsValid() {
+		format += "%s:\n%#v\n"
+		arg = append(arg, e.config.bLabel, e.formatFull(bv))
+	}
+	return format, arg
+}
+
+// write writes formatted output to the sink, subject to
+// the limit set by MaxBytes. If the output would exceed the
+// limit, write discards it, and if isDiff is true, counts it
+// as a dropped difference. It reports whether it wrote
+// the output.
+func (e *printEmitter) write(isDiff bool, format string, arg ...any) bool {
+	e.config.helper()
+	n := e.config.maxBytes
+	if n <= 0 {
+		e.config.sink(format, arg...)
+		return true
+	}
+	s := fmt.Sprintf(format, arg...)
+	if e.out.dropped > 0 || e.out.nbyte+len(s) > n {
+		if isDiff {
+			e.out.dropped++
+		}
+		return false
+	}
+	e.out.nbyte += len(s)
+	e.config.sink("%s", s)
+	return true
+}
+
+func (e *printEmitter) sub(t reflect.Type, s step) emitfer {
+	if e.root == nil {
+		e.root = t
+	}
+	if e.rootType == "" && t != nil && e.config.pathStyle == pathGo {
+		limit := e.config.display.maxTypeLen
+		if e.config.level == full {
+			limit = 0
+		}
 		var buf bytes.Buffer
-		writeType(&buf, t)
+		writeTypeLimit(&buf, t, limit)
 		e.rootType = buf.String()
 	}
-	pe := &printEmitter{
+	return &printEmitter{
 		config:   e.config,
+		root:     e.root,
 		rootType: e.rootType,
-		path:     append(e.path, fmt.Sprintf(format, arg...)),
+		step:     s,
+		depth:    e.depth + 1,
+		parent:   e,
 		did:      false,
+		out:      e.out,
+	}
+}
+
+// formatFull returns a formatter for the full form
+// of v, found at the current location.
+func (e *printEmitter) formatFull(v reflect.Value) fmt.Formatter {
+	if rv, ok := asReportValue(v); ok {
+		return rv
+	}
+	f := formatFull(&e.config.display, v)
+	if e.config.pointerIDs {
+		f.(*formatter).ids = &e.out.ptrIDs
+	}
+	if e.config.display.hook != nil {
+		locate([]any{f}, e.pathValue())
+	}
+	if n := e.config.fullWidth; n > 0 {
+		return clippedFull{f, n}
+	}
+	return f
+}
+
+// pathValue returns the path to the current location.
+func (e *printEmitter) pathValue() Path {
+	return Path{root: e.root, steps: e.steps(), style: e.config.pathStyle}
+}
+
+// pathString returns the path to the current location,
+// including the root type if the path style calls for it.
+func (e *printEmitter) pathString() string {
+	return e.rootType + formatPath(e.config.pathStyle, e.steps())
+}
+
+// treeLabels returns the labels of the nodes
+// on the path to the current location, for EmitTree.
+func (e *printEmitter) treeLabels() []string {
+	if e.depth == 0 {
+		return nil
+	}
+	labels := []string{e.rootType}
+	path := e.steps()
+	for i := range path {
+		if i == 0 && e.config.pathStyle == pathJQ {
+			labels = append(labels, formatPath(pathJQ, path[:1]))
+			continue
+		}
+		var b strings.Builder
+		path[i].writeTo(&b, e.config.pathStyle)
+		labels = append(labels, b.String())
 	}
-	pe.config.sink = func(format string, a ...any) {
-		e.config.helper()
-		e.did = true
-		e.config.sink(format, a...)
+	return labels
+}
+
+// pathCell returns the path column for EmitColumns.
+func (e *printEmitter) pathCell() string {
+	if e.depth == 0 {
+		return ""
+	}
+	return e.pathString() + ":"
+}
+
+// flush writes any output held back until the end of the walk.
+func (e *printEmitter) flush() {
+	e.config.helper()
+	if e.out.table != nil {
+		for _, line := range e.out.table.lines() {
+			e.write(true, "%s\n", line)
+		}
+	}
+	if e.out.tree != nil && e.out.ndiff > 0 {
+		var b strings.Builder
+		e.out.tree.writeTo(&b)
+		e.write(false, "%s", b.String())
+	}
+	if e.out.sides != nil && e.out.ndiff > 0 {
+		lines := e.out.sides.lines
+		if e.config.level == dotGraph {
+			lines = e.out.sides.dot
+		}
+		for _, line := range lines(*e.config) {
+			e.write(false, "%s\n", line)
+		}
+	}
+	if n := e.out.dropped; n > 0 {
+		e.writeSummary("truncated", fmt.Sprintf("output truncated, %d more differences", n))
+	}
+	if t := e.out.timedOut; t > 0 {
+		e.writeSummary("timeout", fmt.Sprintf("comparison timed out after %v and %d differences", t, e.out.ndiff))
+	}
+	if e.out.sarif != nil && e.out.ndiff > 0 {
+		e.write(false, "%s", e.out.sarif.log())
+	}
+	if e.config.level == tap {
+		e.config.sink("%s", e.tapPlan())
 	}
-	return pe
 }
 
 func (e *printEmitter) didEmit() bool {
 	return e.did
 }
 
+// steps returns the path to e. It builds the path
+// the first time, from the steps of e and the emitters
+// it is inside, so sub doesn't need to copy it.
+func (e *printEmitter) steps() []step {
+	if e.path != nil || e.depth == 0 {
+		return e.path
+	}
+	path := make([]step, e.depth)
+	for p := e; p.depth > 0; p = p.parent {
+		if p.path != nil {
+			copy(path, p.path)
+			break
+		}
+		path[p.depth-1] = p.step
+	}
+	e.path = path
+	return path
+}
+
 type countEmitter struct {
 	n int
+
+	// edits counts each element added to or removed from
+	// a slice, rather than counting a change in length once.
+	edits bool
+
+	// track keeps the path to each location, for At.
+	// Otherwise sub returns the same emitter.
+	track bool
+	path  []step
+	root  *countEmitter // holds n, if set
 }
 
-func (e *countEmitter) emitf(av, bv reflect.Value, format string, arg ...any) {
-	e.n++
+// newCounter returns a countEmitter for comparing
+// values at path, outside the output.
+func (d *differ) newCounter(path []step, edits bool) *countEmitter {
+	return &countEmitter{edits: edits, track: d.config.pathScoped, path: path}
 }
 
-func (e *countEmitter) subf(t reflect.Type, format string, arg ...any) emitfer {
+// counter returns the emitter that holds the count for e.
+func (e *countEmitter) counter() *countEmitter {
+	if e.root != nil {
+		return e.root
+	}
 	return e
 }
 
+func (e *countEmitter) emitf(av, bv reflect.Value, format string, arg ...any) {
+	e.counter().n++
+}
+
+func (e *countEmitter) sub(t reflect.Type, s step) emitfer {
+	if !e.track {
+		return e
+	}
+	return &countEmitter{
+		edits: e.edits,
+		track: true,
+		path:  appendStep(e.path, s),
+		root:  e.counter(),
+	}
+}
+
 func (e *countEmitter) didEmit() bool {
-	return e.n > 0
+	return e.counter().n > 0
 }
 
+func (e *countEmitter) steps() []step {
+	return e.path
+}
+
+func (e *countEmitter) notef(format string, arg ...any) {}
+
 func reflectApply(f reflect.Value, v ...reflect.Value) reflect.Value {
 	return f.Call(v)[0]
 }
 
 func newDiffer(h func(), f func(format string, arg ...any), opt ...Option) *differ {
-	d := &differ{
-		aSeen: map[visit]visit{},
-		bSeen: map[visit]visit{},
+	c := newConfig(opt...)
+	c.sink = f
+	c.helper = h
+	return newDifferConfig(c)
+}
+
+// newConfig returns a config with the default options
+// and then opt applied.
+func newConfig(opt ...Option) config {
+	var c config
+	c.sink = func(string, ...any) {}
+	c.helper = func() {}
+	c.xform = map[reflect.Type][]transform{}
+	c.format = map[reflect.Type]reflect.Value{}
+	c.unordered = map[reflect.Type]bool{}
+	c.sliceSets = map[reflect.Type]bool{}
+	c.normKeys = map[reflect.Type]reflect.Value{}
+	c.aLabel = "a"
+	c.bLabel = "b"
+	c.textContext = 3
+	c.display.maxTypeLen = maxAnonType
+	OptionList(defaultOpt, OptionList(opt...)).apply(&c)
+	c.funcs = new(funcCache)
+	return c
+}
+
+// newDifferConfig returns a differ for a single
+// comparison using c.
+func newDifferConfig(c config) *differ {
+	return &differ{
+		config: c,
+		aSeen:  map[visit]visit{},
+		bSeen:  map[visit]visit{},
 	}
-	d.config.sink = f
-	d.config.helper = h
-	d.config.xform = map[reflect.Type]reflect.Value{}
-	d.config.format = map[reflect.Type]reflect.Value{}
-	d.config.aLabel = "a"
-	d.config.bLabel = "b"
-	OptionList(defaultOpt, OptionList(opt...)).apply(&d.config)
-	return d
 }
 
 func (d *differ) each(a, b any) {
 	d.config.helper()
-	e := &printEmitter{config: d.config}
-	av := addressable(reflect.ValueOf(a))
-	bv := addressable(reflect.ValueOf(b))
+	e := d.rootEmitter()
+	if d.config.timeout > 0 {
+		d.deadline = time.Now().Add(d.config.timeout)
+	}
+	av, bv := rootValue(a), rootValue(b)
+	if av.IsValid() {
+		d.root = av.Type()
+	} else if bv.IsValid() {
+		d.root = bv.Type()
+	}
+	if e.out.sides != nil {
+		e.out.sides.a, e.out.sides.b = av, bv
+	}
 	d.walk(e, av, bv, true, true)
+	if d.timedOut {
+		e.out.timedOut = d.config.timeout
+		if d.config.change != nil {
+			d.config.change(Change{
+				Text: fmt.Sprintf("comparison timed out after %v", d.config.timeout),
+			})
+		}
+	}
+	e.flush()
+}
+
+// rootEmitter returns the emitter for the root of the output.
+// The caller must call its flush method when done.
+func (d *differ) rootEmitter() *printEmitter {
+	if d.config.prefix != "" {
+		sink, h := d.config.sink, d.config.helper
+		d.config.sink = func(format string, arg ...any) {
+			h()
+			var buf strings.Builder
+			fmt.Fprintf(indent.New(&buf, d.config.prefix), format, arg...)
+			sink("%s", buf.String())
+		}
+	}
+	c := d.config
+	if c.level == githubActions && c.location.file == "" {
+		c.location = callerLocation()
+	}
+	e := &printEmitter{config: &c, out: &output{stop: &d.stop, color: d.config.useColor()}}
+	switch d.config.level {
+	case columns:
+		e.out.table = new(table)
+	case tree:
+		e.out.tree = new(treeNode)
+	case sideBySide, dotGraph:
+		e.out.sides = new(sideTable)
+	case sarif:
+		e.out.sarif = new(sarifOutput)
+	}
+	return e
 }
 
 func (d *differ) equalAsIs(av, bv reflect.Value) bool {
+	e := d.newCounter(d.path, false)
+	d.fork().walk(e, av, bv, false, true)
+	return !e.didEmit()
+}
+
+// equal reports whether av and bv are equal,
+// applying transforms the same way walk does.
+func (d *differ) equal(av, bv reflect.Value) bool {
+	e := d.newCounter(d.path, false)
+	d.fork().walk(e, av, bv, true, true)
+	return !e.didEmit()
+}
+
+// equalAt is like equal for values one step s
+// below the current location, such as list elements.
+func (d *differ) equalAt(s step, av, bv reflect.Value) bool {
+	e := d.newCounter(appendStep(d.path, s), false)
+	d.fork().walk(e, av, bv, true, true)
+	return !e.didEmit()
+}
+
+// fork returns a differ for making comparisons
+// that are not part of the output.
+// It has the same options as d but its own cycle state.
+// It shares d's record of pairs known to be equal.
+func (d *differ) fork() *differ {
+	if d.memo == nil {
+		d.memo = map[[2]visit]memoState{}
+	}
 	d2 := &differ{
 		config: d.config,
 		aSeen:  map[visit]visit{},
 		bSeen:  map[visit]visit{},
+		memo:   d.memo, // equality doesn't depend on the path
+		root:   d.root,
 	}
 	d2.config.format = nil
-	e := &countEmitter{}
-	d2.walk(e, av, bv, false, true)
-	return !e.didEmit()
+	d2.config.trace = nil
+	d2.config.display.hook = nil // Display doesn't affect equality
+	return d2
 }
 
 func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 	d.config.helper()
+	if d.config.pathScoped {
+		defer func(c config, path []step) {
+			d.config, d.path = c, path
+		}(d.config, d.path)
+		d.path = e.steps()
+		d.config = d.config.scoped(d.path)
+	}
+	if d.stop {
+		return
+	}
+	if !d.deadline.IsZero() || d.ctx != nil {
+		d.nvisit++
+		if d.nvisit%deadlineEvery == 0 {
+			if d.ctx != nil && d.ctx.Err() != nil {
+				d.stop = true
+				return
+			}
+			if !d.deadline.IsZero() && time.Now().After(d.deadline) {
+				d.stop = true
+				d.timedOut = true
+				return
+			}
+		}
+	}
+	if d.fastEqual(av, bv) {
+		return
+	}
 	if !av.IsValid() && !bv.IsValid() {
 		return
 	}
 	if !av.IsValid() || !bv.IsValid() {
-		e.emitf(av, bv, "%v != %v", formatShort(av, true), formatShort(bv, true))
+		if d.config.equalTypedNil && (isNil(av) || isNil(bv)) {
+			d.tracef(e, "nil and typed nil (EqualTypedNil)")
+			return
+		}
+		d.tracef(e, "nil and non-nil")
+		d.emitNE(e, av, bv, true)
 		return
 	}
 
+	if d.config.errorChains {
+		aerr, aok := asError(av)
+		berr, bok := asError(bv)
+		if aok && bok {
+			d.tracef(e, "error chains (ErrorChains)")
+			d.walkErrorChain(e, av, bv, aerr, berr)
+			return
+		}
+	}
+
 	t := av.Type()
 	if t != bv.Type() {
-		e.emitf(av, bv, "%v != %v", formatShort(av, true), formatShort(bv, true))
+		d.tracef(e, "different types %v and %v", t, bv.Type())
+		d.emitNE(e, av, bv, true)
 		return
 	}
 
@@ -263,33 +1090,85 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		}
 		avis := visit{unsafe.Pointer(av.Pointer()), t}
 		bvis := visit{unsafe.Pointer(bv.Pointer()), t}
-		if bSeen, ok := d.aSeen[avis]; ok {
-			if bSeen != bvis {
-				e.emitf(av, bv, "uneven cycle")
-			}
+		if d.config.aliasing {
+			d.checkAliasing(e, av, bv, avis, bvis)
+		}
+		bSeen, aCycle := d.aSeen[avis]
+		_, bCycle := d.bSeen[bvis]
+		switch {
+		case aCycle && bCycle && bSeen == bvis:
+			d.tracef(e, "%v cycle", t)
+			return
+		case aCycle && bCycle:
+			e.emitf(av, bv, "cycles of different lengths")
+			return
+		case aCycle:
+			e.emitf(av, bv, "cycle in %s but not %s", d.config.aLabel, d.config.bLabel)
+			return
+		case bCycle:
+			e.emitf(av, bv, "cycle in %s but not %s", d.config.bLabel, d.config.aLabel)
 			return
 		}
-		if _, ok := d.bSeen[bvis]; ok {
-			e.emitf(av, bv, "uneven cycle")
+		// Only values on the path from the root form cycles.
+		// A value reachable by more than one path is compared
+		// each time it's reached, like any other, but if the
+		// same pair turns out to be equal, that is remembered,
+		// unless the options depend on the path (see At).
+		if xformOk && !d.config.pathScoped && d.memoEqual(av, bv, avis, bvis) {
+			d.tracef(e, "%v pair already found equal", t)
 			return
 		}
 		d.aSeen[avis] = bvis
 		d.bSeen[bvis] = avis
+		d.walkValue(e, av, bv, t, xformOk, wantType)
+		delete(d.aSeen, avis)
+		delete(d.bSeen, bvis)
+		return
+	}
+	d.walkValue(e, av, bv, t, xformOk, wantType)
+}
+
+// walkValue compares av and bv, of type t,
+// after walk has checked for cycles.
+func (d *differ) walkValue(e emitfer, av, bv reflect.Value, t reflect.Type, xformOk, wantType bool) {
+	d.config.helper()
+
+	if d.config.emitEqual && isLeaf(d.config, av) {
+		if firstNote(e) {
+			defer d.noteEqual(e, av)
+		}
+	}
+
+	if d.config.display.hook != nil && d.displayed(e, av, bv) {
+		return
+	}
+
+	if d.config.ignoreZeroTime && (isZeroTime(av) || isZeroTime(bv)) {
+		d.tracef(e, "%v zero time, equal (IgnoreZeroTime)", t)
+		return
+	}
+
+	if d.config.durationTolerance > 0 && t == reflectDuration && withinDuration(av, bv, d.config.durationTolerance) {
+		d.tracef(e, "%v within tolerance, equal (DurationTolerance)", t)
+		return
 	}
 
 	// Check for a transform func.
 	didXform := false
-	if xf, haveXform := d.config.xform[t]; xformOk && haveXform {
-		ax := addressable(reflectApply(xf, av).Elem())
-		bx := addressable(reflectApply(xf, bv).Elem())
+	if xf, haveXform := d.config.xformFunc(t); xformOk && haveXform {
+		ax := applyChain(xf, av)
+		bx := applyChain(xf, bv)
 		if d.equalAsIs(ax, bx) {
+			d.tracef(e, "%v transformed, equal", t)
 			return
 		}
+		d.tracef(e, "%v transformed, not equal", t)
 		didXform = true
 	}
 
 	// Check for a format func.
-	if ff, ok := d.config.format[t]; ok {
+	if ff, ok := d.config.formatFunc(t); ok {
+		d.tracef(e, "%v format func", t)
 		if didXform || !d.equalAsIs(av, bv) {
 			s := reflectApply(ff, av, bv).String()
 			e.emitf(av, bv, "%s", s)
@@ -297,22 +1176,86 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		return
 	}
 
+	if d.config.equalLocks && isLock(t) {
+		d.tracef(e, "%v lock, equal (EqualLocks)", t)
+		return
+	}
+
+	if d.config.binaryMarshal && !didXform && d.walkBinary(e, av, bv) {
+		return
+	}
+
+	// Check for a comparer for the whole kind.
+	if eq, ok := d.config.kindEq[t.Kind()]; ok {
+		if !eq(av, bv) {
+			d.tracef(e, "%v kind comparer, not equal", t)
+			d.emitNE(e, av, bv, wantType)
+		}
+		return
+	}
+
+	// Compare atomic values by what they hold,
+	// not by their internal representation.
+	if isAtomic(t) {
+		ax, aok := atomicLoad(av)
+		bx, bok := atomicLoad(bv)
+		if aok && bok {
+			d.tracef(e, "%v loaded", t)
+			d.walk(e, ax, bx, true, wantType)
+			return
+		}
+	}
+
 	// We use almost the same rules as reflect.DeepEqual here,
 	// but with a couple of configuration options that modify
 	// the behavior, such as:
 	//   * We allow the client to ignore functions.
 	// See "go doc reflect DeepEqual" for more.
+	d.tracef(e, "%v", t)
 	switch t.Kind() {
 	case reflect.Array:
-		// TODO(kr): fancy diff (histogram, myers)
-		for i := 0; i < t.Len(); i++ {
-			d.walk(e.subf(t, "[%d]", i), av.Index(i), bv.Index(i), true, false)
+		if vt := d.config.vector; vt != nil && isFloatVector(t) {
+			d.walkVector(e, av, bv, vt)
+			break
 		}
+		// TODO(kr): fancy diff (histogram, myers)
+		d.walkElems(e, t, av, bv, t.Len())
 	case reflect.Struct:
-		for i := 0; i < t.NumField(); i++ {
-			afield := access(av.Field(i))
-			bfield := access(bv.Field(i))
-			d.walk(e.subf(t, "."+t.Field(i).Name), afield, bfield, true, false)
+		plan := structPlan(t)
+		if d.config.sortFields {
+			plan = sortedStructPlan(t)
+		}
+		for _, f := range plan {
+			afield := access(av.Field(f.index))
+			bfield := access(bv.Field(f.index))
+			if f.tag.ignoreZero && (afield.IsZero() || bfield.IsZero()) {
+				if d.config.trace != nil {
+					d.tracef(e.sub(t, f.step), "skipped, zero (tag ignorezero)")
+				}
+				continue
+			}
+			if f.tag.redact || d.config.display.redact[t.Field(f.index).Name] {
+				if !d.equalAt(f.step, afield, bfield) {
+					e.sub(t, f.step).emitf(redactValue(afield), redactValue(bfield), "%s != %s", redacted, redacted)
+				} else if d.config.emitEqual {
+					e.sub(t, f.step).notef("= %s", redacted)
+				}
+				continue
+			}
+			if f.tag.within(afield, bfield) {
+				if d.config.trace != nil {
+					d.tracef(e.sub(t, f.step), "skipped, within tolerance (tag)")
+				}
+				continue
+			}
+			if d.config.queryValues && isRawQuery(t, f.index) {
+				if aq, bq, ok := parseQueries(afield, bfield); ok {
+					d.tracef(e.sub(t, f.step), "compared as a query (QueryValues)")
+					d.walkQuery(e.sub(t, f.step), aq, bq)
+					continue
+				}
+			}
+			d.walk(e.sub(t, f.step), afield, bfield, true, false)
 		}
 	case reflect.Func:
 		if d.config.equalFuncs {
@@ -326,6 +1269,21 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		belem := addressable(bv.Elem())
 		d.walk(e, aelem, belem, xformOk, true)
 	case reflect.Map:
+		if d.config.queryValues && t == reflectValues && !av.IsNil() && !bv.IsNil() {
+			d.tracef(e, "compared as a query (QueryValues)")
+			d.walkQuery(e, av, bv)
+			break
+		}
+		if d.config.headers && isHeaderType(t) && !av.IsNil() && !bv.IsNil() {
+			d.tracef(e, "compared as headers (Headers)")
+			d.walkHeader(e, t, av, bv)
+			break
+		}
+		if d.config.mapSets && isSetType(t) {
+			d.tracef(e, "compared as a set (MapSets)")
+			d.walkSet(e, t, av, bv)
+			break
+		}
 		if av.IsNil() != bv.IsNil() {
 			d.emitPointers(e, av, bv, wantType)
 			break
@@ -333,23 +1291,49 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		if av.Pointer() == bv.Pointer() {
 			break
 		}
+		if f, ok := d.config.normKeys[t.Key()]; ok {
+			d.tracef(e, "keys normalized (NormalizeKeys)")
+			av = d.normalizeKeys(e, t, f, av, d.config.aLabel)
+			bv = d.normalizeKeys(e, t, f, bv, d.config.bLabel)
+		}
+		if d.config.pointerKeys && t.Key().Kind() == reflect.Ptr {
+			d.tracef(e, "keys matched by pointee (PointerKeys)")
+			d.walkPointerKeys(e, t, av, bv)
+			break
+		}
 
-		for _, k := range sortedKeys(av, bv) {
-			esub := e.subf(t, "[%#v]", k)
-			if av.MapIndex(k).IsValid() && bv.MapIndex(k).IsValid() {
-				d.walk(esub, av.MapIndex(k), bv.MapIndex(k), true, false)
-			} else if av.MapIndex(k).IsValid() {
-				esub.emitf(av.MapIndex(k), bv.MapIndex(k), "(removed)")
+		var r *recorder
+		me := e // receives the entries
+		if _, counting := e.(*countEmitter); !counting && d.config.summarizing() {
+			r = newRecorder(e.steps(), d.failStop())
+			me = r
+		}
+		for _, ent := range keyEntries(av, bv) {
+			if d.stop {
+				break
+			}
+			esub := me.sub(t, keyStep(ent.key))
+			if ent.a.IsValid() && ent.b.IsValid() {
+				d.walk(esub, ent.a, ent.b, true, false)
+			} else if ent.a.IsValid() {
+				esub.emitf(ent.a, ent.b, "(removed)")
 			} else { // k in bv
-				esub.emitf(av.MapIndex(k), bv.MapIndex(k), "(added) %v", formatShort(bv.MapIndex(k), false))
+				esub.emitf(ent.a, ent.b, "(added) %v", formatShort(&d.config.display, ent.b, false))
+			}
+		}
+		d.walkSelfUnequalKeys(me, t, av, bv)
+		if r != nil {
+			if !d.stop {
+				r.summarize(e, &d.config, av.Len(), bv.Len())
 			}
+			r.replayAll(e)
 		}
 	case reflect.Ptr:
 		if av.Pointer() == bv.Pointer() {
 			break
 		}
 		if av.IsNil() != bv.IsNil() {
-			e.emitf(av, bv, "%v != %v", formatShort(av, wantType), formatShort(bv, wantType))
+			e.emitf(av, bv, "%v != %v", formatShort(&d.config.display, av, wantType), formatShort(&d.config.display, bv, wantType))
 			break
 		}
 		d.walk(e, av.Elem(), bv.Elem(), true, wantType)
@@ -361,6 +1345,20 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		if av.Len() == bv.Len() && av.Pointer() == bv.Pointer() {
 			break
 		}
+		if vt := d.config.vector; vt != nil && isFloatVector(t) && av.Len() == bv.Len() {
+			d.walkVector(e, av, bv, vt)
+			break
+		}
+		if d.config.sliceSets[t] {
+			d.tracef(e, "compared as a set (SliceSet)")
+			d.walkSliceSet(e, t, av, bv)
+			break
+		}
+		if d.config.unordered[t] {
+			d.tracef(e, "compared unordered (Unordered)")
+			d.walkUnordered(e, t, av, bv)
+			break
+		}
 		if t.ConvertibleTo(reflectBytes) {
 			as := av.Convert(reflectString)
 			bs := bv.Convert(reflectString)
@@ -370,19 +1368,27 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		// TODO(kr): fancy diff (histogram, myers)
 		n := av.Len()
 		if blen := bv.Len(); n != blen {
+			if ce, ok := e.(*countEmitter); ok && ce.edits {
+				d.countEdits(ce, av, bv)
+				return
+			}
 			e.emitf(av, bv, "{len %d} != {len %d}", n, blen)
 			return
 		}
-		for i := 0; i < n; i++ {
-			d.walk(e.subf(t, "[%d]", i), av.Index(i), bv.Index(i), true, false)
-		}
+		d.walkElems(e, t, av, bv, n)
 	case reflect.Bool:
 		d.eqtest(e, av, bv, av.Bool(), bv.Bool(), wantType)
 	case reflect.Int, reflect.Int8, reflect.Int16,
 		reflect.Int32, reflect.Int64:
 		d.eqtest(e, av, bv, av.Int(), bv.Int(), wantType)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16,
-		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		reflect.Uint32, reflect.Uint64:
+		d.eqtest(e, av, bv, av.Uint(), bv.Uint(), wantType)
+	case reflect.Uintptr:
+		if d.config.uintptrHandles {
+			d.eqtest(e, av, bv, av.Uint() == 0, bv.Uint() == 0, wantType)
+			break
+		}
 		d.eqtest(e, av, bv, av.Uint(), bv.Uint(), wantType)
 	case reflect.Float32, reflect.Float64:
 		d.eqtest(e, av, bv, av.Float(), bv.Float(), wantType)
@@ -390,7 +1396,13 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		d.eqtest(e, av, bv, av.Complex(), bv.Complex(), wantType)
 	case reflect.String:
 		d.stringDiff(e, av, bv, av.String(), bv.String())
-	case reflect.Chan, reflect.UnsafePointer:
+	case reflect.Chan:
+		if d.config.chanMetadata {
+			d.walkChanMetadata(e, av, bv, wantType)
+			break
+		}
+		fallthrough
+	case reflect.UnsafePointer:
 		if a, b := av.Pointer(), bv.Pointer(); a != b {
 			d.emitPointers(e, av, bv, wantType)
 		}
@@ -407,57 +1419,384 @@ func (d *differ) walk(e emitfer, av, bv reflect.Value, xformOk, wantType bool) {
 		var buf bytes.Buffer
 		writeType(&buf, t)
 		e.emitf(av, bv, "warning: %s transform is impure", buf.String())
-		e.emitf(av, bv, "%v != %v", formatShort(av, wantType), formatShort(bv, wantType))
+		e.emitf(av, bv, "%v != %v", formatShort(&d.config.display, av, wantType), formatShort(&d.config.display, bv, wantType))
+	}
+
+	if pe, ok := e.(*printEmitter); ok && pe.nhidden > 0 {
+		pe.notef("…and %d more in this %s", pe.nhidden, t.Kind())
+		pe.nhidden = 0
+	}
+}
+
+// failStop returns the stop flag for recorders
+// to set on finding a difference, if FailFast is on.
+func (d *differ) failStop() *bool {
+	if d.config.failFast {
+		return &d.stop
+	}
+	return nil
+}
+
+// walkElems walks the first n elements of av and bv,
+// which must be arrays or slices of type t.
+func (d *differ) walkElems(e emitfer, t reflect.Type, av, bv reflect.Value, n int) {
+	d.config.helper()
+	_, counting := e.(*countEmitter)
+	aggregating := d.config.aggregate > 0 && d.config.showsNotes()
+	if !counting && (aggregating || d.config.summarizing()) {
+		r := newRecorder(e.steps(), d.failStop())
+		d.walkElemsTo(r, t, av, bv, n)
+		// A walk cut short, as by FailFast,
+		// has no complete shape to summarize.
+		if d.config.summarizing() && !d.stop {
+			r.summarize(e, &d.config, av.Len(), bv.Len())
+		}
+		if aggregating {
+			r.replayAggregated(e, d.config.aggregate)
+		} else {
+			r.replayAll(e)
+		}
+		return
+	}
+	d.walkElemsTo(e, t, av, bv, n)
+}
+
+func (d *differ) walkElemsTo(e emitfer, t reflect.Type, av, bv reflect.Value, n int) {
+	d.config.helper()
+	if d.config.collapse == 0 && d.config.elemContext == 0 {
+		for i := 0; i < n; i++ {
+			d.walk(e.sub(t, indexStep(i)), av.Index(i), bv.Index(i), true, false)
+		}
+		return
+	}
+
+	eq := make([]bool, n)
+	alleq := true
+	for i := range eq {
+		eq[i] = d.equalAt(indexStep(i), av.Index(i), bv.Index(i))
+		alleq = alleq && eq[i]
+	}
+	if alleq {
+		return
+	}
+
+	// Mark equal elements near a difference as context.
+	near := make([]bool, n)
+	for i := range eq {
+		if eq[i] {
+			continue
+		}
+		lo, hi := i-d.config.elemContext, i+d.config.elemContext
+		if lo < 0 {
+			lo = 0
+		}
+		for j := lo; j <= hi && j < n; j++ {
+			near[j] = eq[j]
+		}
+	}
+
+	for i := 0; i < n; {
+		if !eq[i] {
+			d.walk(e.sub(t, indexStep(i)), av.Index(i), bv.Index(i), true, false)
+			i++
+			continue
+		}
+		if near[i] {
+			e.sub(t, indexStep(i)).notef("(context) %v", formatShort(&d.config.display, av.Index(i), false))
+			i++
+			continue
+		}
+		j := i
+		for j < n && eq[j] && !near[j] {
+			j++
+		}
+		if d.config.collapse > 0 && j-i >= d.config.collapse {
+			e.sub(t, rangeStep(i, j-1)).notef("(equal, %d elements)", j-i)
+		}
+		i = j
 	}
 }
 
 func (d *differ) eqtest(e emitfer, av, bv reflect.Value, a, b any, wantType bool) {
 	d.config.helper()
 	if a != b {
-		e.emitf(av, bv, "%v != %v",
-			formatShort(av, wantType),
-			formatShort(bv, wantType),
-		)
+		d.emitNE(e, av, bv, wantType)
 	}
 }
 
 func (d *differ) emitPointers(e emitfer, av, bv reflect.Value, wantType bool) {
 	d.config.helper()
-	e.emitf(av, bv, "%v != %v",
-		formatShort(av, wantType),
-		formatShort(bv, wantType),
-	)
+	d.emitNE(e, av, bv, wantType)
+}
+
+// walkChanMetadata compares channels av and bv
+// by nil-ness, capacity, and current length, for ChanMetadata.
+// Their direction is part of their type.
+func (d *differ) walkChanMetadata(e emitfer, av, bv reflect.Value, wantType bool) {
+	if av.IsNil() != bv.IsNil() {
+		d.emitPointers(e, av, bv, wantType)
+		return
+	}
+	if a, b := av.Cap(), bv.Cap(); a != b {
+		e.emitf(av, bv, "cap %d != %d", a, b)
+	}
+	if a, b := av.Len(), bv.Len(); a != b {
+		e.emitf(av, bv, "len %d != %d", a, b)
+	}
+}
+
+type memoState int
+
+const (
+	memoSeen memoState = 1 + iota
+	memoPending
+	memoEqual
+	memoUnequal
+)
+
+// memoEqual reports whether the pair of pointers avis and
+// bvis is known to point to equal values av and bv.
+// The first time a pair is reached, it is not known.
+// The second time, memoEqual compares the values
+// and remembers the result, so any later time is cheap.
+// This makes comparing a graph with many shared nodes
+// take time in proportion to the number of nodes,
+// rather than the number of paths to them.
+func (d *differ) memoEqual(av, bv reflect.Value, avis, bvis visit) bool {
+	if d.memo == nil {
+		d.memo = map[[2]visit]memoState{}
+	}
+	key := [2]visit{avis, bvis}
+	switch d.memo[key] {
+	case memoEqual:
+		return true
+	case memoSeen:
+		d.memo[key] = memoPending // don't recurse into this check
+		if d.equal(av, bv) {
+			d.memo[key] = memoEqual
+			return true
+		}
+		d.memo[key] = memoUnequal
+	case 0:
+		d.memo[key] = memoSeen
+	}
+	return false
+}
+
+// An alias records where a pointer was first seen,
+// for Aliasing: the pointer it was paired with
+// on the other side, and the path to it.
+type alias struct {
+	other visit
+	path  string
+}
+
+// checkAliasing emits a difference if pointers avis and bvis
+// are not shared the same way in a and b: if one of them
+// has been seen before, paired with a different pointer.
+// The difference names the path where the shared
+// pointer was seen before.
+func (d *differ) checkAliasing(e emitfer, av, bv reflect.Value, avis, bvis visit) {
+	if d.aShared == nil {
+		d.aShared = map[visit]alias{}
+		d.bShared = map[visit]alias{}
+	}
+	aPrev, aOld := d.aShared[avis]
+	bPrev, bOld := d.bShared[bvis]
+	aShared := aOld && aPrev.other != bvis // shared in a, not b the same way
+	bShared := bOld && bPrev.other != avis // shared in b, not a the same way
+	switch {
+	case aShared && bShared:
+		e.emitf(av, bv, "aliasing differs: shared with %s in %s but with %s in %s",
+			aPrev.path, d.config.aLabel, bPrev.path, d.config.bLabel)
+	case aShared:
+		e.emitf(av, bv, "aliasing differs: shared with %s in %s but not %s",
+			aPrev.path, d.config.aLabel, d.config.bLabel)
+	case bShared:
+		e.emitf(av, bv, "aliasing differs: shared with %s in %s but not %s",
+			bPrev.path, d.config.bLabel, d.config.aLabel)
+	}
+	if !aOld || !bOld {
+		p := "(root)"
+		if pe, ok := e.(*printEmitter); ok && pe.depth > 0 {
+			p = pe.pathString()
+		}
+		if !aOld {
+			d.aShared[avis] = alias{bvis, p}
+		}
+		if !bOld {
+			d.bShared[bvis] = alias{avis, p}
+		}
+	}
+}
+
+// tracef writes a line to the trace writer, if any,
+// describing what walk is doing at e's path.
+func (d *differ) tracef(e emitfer, format string, arg ...any) {
+	if d.config.trace == nil {
+		return
+	}
+	p := "?"
+	if pe, ok := e.(*printEmitter); ok {
+		p = pe.pathString()
+		if p == "" {
+			p = "(root)"
+		}
+	}
+	fmt.Fprintf(d.config.trace, "%s: %s\n", p, fmt.Sprintf(format, arg...))
+}
+
+// noteEqual notes the value av at e, for EmitEqual,
+// if no difference was found there.
+func (d *differ) noteEqual(e emitfer, av reflect.Value) {
+	d.config.helper()
+	if e.didEmit() {
+		return
+	}
+	f := formatShort(&d.config.display, av, false)
+	if d.config.display.hook != nil {
+		locate([]any{f}, d.pathAt(e))
+	}
+	e.notef("= %v", f)
+}
+
+// firstNote reports whether e is yet to have a note
+// for EmitEqual, and marks it as having one, so a value
+// walked more than once at the same place, such as
+// one with a transform, is noted only once.
+func firstNote(e emitfer) bool {
+	switch e := e.(type) {
+	case *printEmitter:
+		if e.noted {
+			return false
+		}
+		e.noted = true
+		return true
+	case *recorder:
+		if e.noted {
+			return false
+		}
+		e.noted = true
+		return true
+	}
+	return false
+}
+
+// pathAt returns the path to the location of e.
+func (d *differ) pathAt(e emitfer) Path {
+	return Path{root: d.root, steps: e.steps(), style: d.config.pathStyle}
+}
+
+// emitNE emits a difference between av and bv
+// in the form "a != b". See distinctShort.
+func (d *differ) emitNE(e emitfer, av, bv reflect.Value, wantType bool) {
+	d.config.helper()
+	if _, ok := e.(*countEmitter); ok {
+		e.emitf(av, bv, "") // no need to format the values
+		return
+	}
+	a, b := distinctShort(&d.config.display, av, bv, wantType)
+	e.emitf(av, bv, "%v != %v", a, b)
 }
 
 func (d *differ) stringDiff(e emitfer, av, bv reflect.Value, a, b string) {
 	d.config.helper()
 
+	a, b = d.normalized(a), d.normalized(b)
 	if a == b {
 		return
 	}
+	if _, ok := e.(*countEmitter); ok {
+		e.emitf(av, bv, "") // no need to find the details
+		return
+	}
 
 	u := utf8.ValidString(a) && utf8.ValidString(b)
 	if !u {
 		// TODO(kr): binary diff, hex, something
-		e.emitf(av, bv, "binary: %+q != %+q", a, b)
+		ea, eb, _ := elide(a, b)
+		e.emitf(av, bv, "binary: %v != %v", ea, eb)
 		return
 	}
 
 	d.textDiff(e, av, bv, a, b)
 }
 
+// sortedKeys returns the keys of maps, in sorted order,
+// omitting keys that are not equal to themselves.
 func sortedKeys(maps ...reflect.Value) []reflect.Value {
 	t := reflect.MapOf(maps[0].Type().Key(), reflectBool)
 	merged := reflect.MakeMap(t)
 	for _, m := range maps {
 		iter := m.MapRange()
 		for iter.Next() {
+			if isSelfUnequal(iter.Key()) {
+				continue // see walkSelfUnequalKeys
+			}
 			merged.SetMapIndex(iter.Key(), reflectTrue)
 		}
 	}
 	return fmtsort.Sort(merged).Key
 }
 
+// A keyEntry holds a key and its values in two maps.
+// A value is the zero Value if the key is missing
+// from that map.
+type keyEntry struct {
+	key, a, b reflect.Value
+}
+
+// keyEntries returns the entries of av and bv, sorted by key,
+// omitting keys that are not equal to themselves.
+// It reads each map once, with no lookups into av or bv.
+func keyEntries(av, bv reflect.Value) []keyEntry {
+	t := reflect.MapOf(av.Type().Key(), reflectInt)
+	index := reflect.MakeMapWithSize(t, av.Len())
+	ents := make([]keyEntry, 0, av.Len())
+	iter := av.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		if isSelfUnequal(k) {
+			continue // see walkSelfUnequalKeys
+		}
+		index.SetMapIndex(k, reflect.ValueOf(len(ents)))
+		ents = append(ents, keyEntry{key: k, a: iter.Value()})
+	}
+	iter = bv.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		if isSelfUnequal(k) {
+			continue
+		}
+		if i := index.MapIndex(k); i.IsValid() {
+			ents[i.Int()].b = iter.Value()
+			continue
+		}
+		index.SetMapIndex(k, reflect.ValueOf(len(ents)))
+		ents = append(ents, keyEntry{key: k, b: iter.Value()})
+	}
+	sorted := entriesByKey{ents, fmtsort.SortedMap{Key: make([]reflect.Value, len(ents))}}
+	for i, ent := range ents {
+		sorted.keys.Key[i] = ent.key
+	}
+	sort.Sort(sorted)
+	return ents
+}
+
+// entriesByKey sorts entries in the order of fmtsort.
+// It keeps the keys in a SortedMap to use its Less.
+type entriesByKey struct {
+	ents []keyEntry
+	keys fmtsort.SortedMap
+}
+
+func (s entriesByKey) Len() int           { return len(s.ents) }
+func (s entriesByKey) Less(i, j int) bool { return s.keys.Less(i, j) }
+func (s entriesByKey) Swap(i, j int) {
+	s.ents[i], s.ents[j] = s.ents[j], s.ents[i]
+	s.keys.Key[i], s.keys.Key[j] = s.keys.Key[j], s.keys.Key[i]
+}
+
 func addressable(r reflect.Value) reflect.Value {
 	if !r.IsValid() {
 		return r
@@ -467,6 +1806,60 @@ func addressable(r reflect.Value) reflect.Value {
 	return a
 }
 
+// isNil reports whether v is a nil pointer, map, slice,
+// channel, func, or interface.
+// It returns false for the zero Value.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice,
+		reflect.Chan, reflect.Func, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isZeroTime reports whether v is a zero time.Time,
+// or a nil or non-nil pointer to one, for IgnoreZeroTime.
+func isZeroTime(v reflect.Value) bool {
+	switch v.Type() {
+	case reflectTime:
+		return v.Interface().(time.Time).IsZero()
+	case reflectTimePtr:
+		return v.IsNil() || v.Elem().Interface().(time.Time).IsZero()
+	}
+	return false
+}
+
+// valueInterface returns the value held in v,
+// or nil if v is the zero Value.
+func valueInterface(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// rootValue returns an addressable Value holding x.
+// If x is itself a reflect.Value, rootValue uses the value
+// it holds rather than the reflect.Value struct. That value
+// may have been obtained through unexported struct fields,
+// as long as it is addressable.
+func rootValue(x any) reflect.Value {
+	v, ok := x.(reflect.Value)
+	if !ok {
+		return addressable(reflect.ValueOf(x))
+	}
+	switch {
+	case !v.IsValid():
+		return v
+	case v.CanAddr():
+		return access(v)
+	case v.CanInterface():
+		return addressable(v)
+	}
+	panic("diff: reflect.Value argument is unexported and not addressable")
+}
+
 func access(v reflect.Value) reflect.Value {
 	p := unsafe.Pointer(v.UnsafeAddr())
 	return reflect.NewAt(v.Type(), p).Elem()
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -307,6 +308,87 @@ func TestLog(t *testing.T) {
 	}
 }
 
+func TestMust(t *testing.T) {
+	type T struct{ A, B int }
+	diff.Must(T{1, 2}, T{1, 2})
+
+	defer func() {
+		got := recover()
+		want := "diff: values differ:\ndiff_test.T.A: 1 != 3\ndiff_test.T.B: 2 != 4"
+		if got != want {
+			t.Errorf("Must panicked with %q, want %q", got, want)
+		}
+	}()
+	diff.Must(T{1, 2}, T{3, 4})
+}
+
+// cleanupT records the calls made by AtCleanup.
+type cleanupT struct {
+	cleanup []func()
+	errors  string
+}
+
+func (c *cleanupT) Helper()          {}
+func (c *cleanupT) Cleanup(f func()) { c.cleanup = append(c.cleanup, f) }
+func (c *cleanupT) Errorf(format string, arg ...any) {
+	c.errors += fmt.Sprintf(format, arg...)
+}
+
+func TestAtCleanup(t *testing.T) {
+	ct := new(cleanupT)
+	var state []string
+	diff.AtCleanup(ct, func() any { return state }, []string{"a", "b"})
+	state = append(state, "a")
+	if len(ct.cleanup) != 1 || ct.errors != "" {
+		t.Fatalf("AtCleanup compared before cleanup")
+	}
+	ct.cleanup[0]()
+	want := "{len 1} != {len 2}\n"
+	if ct.errors != want {
+		t.Errorf("errors = %q, want %q", ct.errors, want)
+	}
+
+	t.Run("pass", func(t *testing.T) {
+		n := 0
+		diff.AtCleanup(t, func() any { return n }, 1)
+		n++
+	})
+}
+
+func TestTestCases(t *testing.T) {
+	type Result struct {
+		N   int
+		Err string
+	}
+	cases := []struct {
+		name string
+		want Result
+	}{
+		{"empty input", Result{N: 0}},
+		{"one", Result{N: 1}},
+		{"bad", Result{Err: "bad"}},
+	}
+	var want []Result
+	for _, tc := range cases {
+		want = append(want, tc.want)
+	}
+	name := func(i int) string { return cases[i].name }
+
+	diff.TestCases(t, t.Errorf, want, want, name)
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.TestCases(t, func(format string, arg ...any) { gotp.Printf(format, arg...) },
+		[]Result{{N: 1}, {N: 1}}, want, name)
+	wantOut := "got 2 cases, want 3\n" +
+		"case \"empty input\": diff_test.Result.N: 1 != 0\n"
+	if got != wantOut {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", wantOut)
+	}
+}
+
 func TestTransformUnexported(t *testing.T) {
 	type T struct{ v time.Time }
 	diff.Test(t, t.Errorf, &T{}, &T{})
@@ -380,3 +462,275 @@ func (sp *stringPrinter) Printf(format string, arg ...any) (int, error) {
 	*(*string)(sp) += s
 	return len(s), nil
 }
+
+func TestColumns(t *testing.T) {
+	type T struct {
+		Name  string
+		Items []int
+		M     map[string]int
+	}
+	a := T{Name: "a", Items: []int{1, 22}, M: map[string]int{"x": 1}}
+	b := T{Name: "b", Items: []int{1, 3}, M: map[string]int{}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitColumns)
+	want := `diff_test.T.Name:     "a" != "b"` + "\n" +
+		`diff_test.T.Items[1]: 22  != 3` + "\n" +
+		`diff_test.T.M["x"]:   (removed)` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestPathJQ(t *testing.T) {
+	type Item struct {
+		Name string `json:"name"`
+	}
+	type T struct {
+		Items []Item          `json:"items"`
+		Attrs map[string]bool `json:"attrs,omitempty"`
+		Count int
+	}
+	a := T{Items: []Item{{"x"}}, Attrs: map[string]bool{"content-type": true}}
+	b := T{Items: []Item{{"y"}}, Attrs: map[string]bool{"content-type": false}, Count: 1}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitPathOnly, diff.PathJQ)
+	want := ".items[0].name\n" +
+		".attrs[\"content-type\"]\n" +
+		".Count\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestPathJSONPointer(t *testing.T) {
+	type Item struct {
+		Name string `json:"name"`
+	}
+	type T struct {
+		Items []Item          `json:"items"`
+		Attrs map[string]bool `json:"attrs"`
+	}
+	a := T{Items: []Item{{"x"}}, Attrs: map[string]bool{"a/b~c": true}}
+	b := T{Items: []Item{{"y"}}, Attrs: map[string]bool{"a/b~c": false}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitPathOnly, diff.PathJSONPointer)
+	want := "/items/0/name\n" +
+		"/attrs/a~1b~0c\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestTree(t *testing.T) {
+	type Item struct {
+		Name  string
+		Price int
+	}
+	type Owner struct{ Email string }
+	type Order struct {
+		Items []Item
+		Owner Owner
+	}
+	a := Order{Items: []Item{{"a", 5}, {"b", 5}}, Owner: Owner{"a@example.com"}}
+	b := Order{Items: []Item{{"a", 5}, {"c", 6}}, Owner: Owner{"b@example.com"}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitTree)
+	want := "diff_test.Order\n" +
+		tab + ".Items[1]\n" +
+		tab + tab + `.Name: "b" != "c"` + "\n" +
+		tab + tab + ".Price: 5 != 6\n" +
+		tab + `.Owner.Email: "a@example.com" != "b@example.com"` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestEachT(t *testing.T) {
+	type T struct{ A, B int }
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.EachT(gotp.Printf, T{1, 2}, T{1, 3})
+	want := "diff_test.T.B: 2 != 3\n"
+	if got != want {
+		t.Errorf("EachT = %q, want %q", got, want)
+	}
+}
+
+func TestTestT(t *testing.T) {
+	var got []string
+	f := func(format string, arg ...any) {
+		got = append(got, fmt.Sprintf(format, arg...))
+	}
+	diff.TestT(t, f, []int{1, 2}, []int{1, 2})
+	if len(got) != 0 {
+		t.Errorf("TestT equal values: got %q, want nothing", got)
+	}
+	diff.TestT(t, f, 1, 2)
+	if want := []string{"int(1) != int(2)\n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TestT = %q, want %q", got, want)
+	}
+}
+
+func TestLookAlike(t *testing.T) {
+	type T struct{ F func() }
+	f := func() {}
+	cases := []struct {
+		name string
+		a, b any
+		opt  []diff.Option
+		want string
+	}{
+		{"func", T{f}, T{f}, nil,
+			"diff_test.T.F: func() {...} != func() {...} (non-nil funcs are never equal; see EqualFuncs)\n"},
+		{"type", lookAlikeA(), lookAlikeB(), nil,
+			"diff_test.L{N:1} (type kr.dev/diff_test.L) != diff_test.L{N:1} (type kr.dev/diff_test.L)\n"},
+		{"crlf", "a\nb\nc\n", "a\r\nb\nc\n", nil,
+			"\"a\\nb\\nc\\n\" != \"a\\r\\nb\\nc\\n\"\n"},
+		{"crlf long", "one\ntwo\nthree\nfour\n", "one\r\ntwo\nthree\nfour\n", nil,
+			"string[3:3]: \"\" != \"\\r\"\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func lookAlikeA() any {
+	type L struct{ N int }
+	return L{1}
+}
+
+func lookAlikeB() any {
+	type L struct{ N int }
+	return L{1}
+}
+
+func TestEmitLevels(t *testing.T) {
+	type T struct{ Name string }
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.EmitAuto, "diff_test.T.Name: \"a\" != \"b\"\n"},
+		{diff.EmitPathOnly, "diff_test.T.Name\n"},
+		{diff.EmitFull, "diff_test.T:\na.Name:\n" + tab + "\"a\"\nb.Name:\n" + tab + "\"b\"\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, T{"a"}, T{"b"}, tt.opt)
+		if got != tt.want {
+			t.Errorf("bad diff")
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+}
+
+func TestReflectValueArgs(t *testing.T) {
+	type T struct {
+		n int
+		s []string
+	}
+	a := &T{1, []string{"x"}}
+	b := &T{2, []string{"x"}}
+	av, bv := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, av, bv)
+	want := "diff_test.T.n: 1 != 2\n"
+	if got != want {
+		t.Errorf("Each(Value, Value) = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, av.Field(1), bv.Field(1)) // unexported
+	if got != "" {
+		t.Errorf("Each(unexported field) = %q, want empty", got)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, reflect.ValueOf(1), 2)
+	want = "int(1) != int(2)\n"
+	if got != want {
+		t.Errorf("Each(Value, int) = %q, want %q", got, want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Each(unaddressable unexported field) did not panic")
+		}
+	}()
+	diff.Each(gotp.Printf, reflect.ValueOf(*a).Field(0), 1)
+}
+
+func BenchmarkMaps(b *testing.B) {
+	f := func(string, ...any) (int, error) { return 0, nil }
+	x := make(map[int]string)
+	y := make(map[int]string)
+	for i := 0; i < 10000; i++ {
+		x[i] = "value"
+		y[i] = "value"
+	}
+	b.Run("equal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			diff.Each(f, x, y)
+		}
+	})
+	z := make(map[int]string)
+	for i := 0; i < 10000; i++ {
+		z[i+5000] = "other"
+	}
+	b.Run("unequal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			diff.Each(f, x, z)
+		}
+	})
+}
+
+func BenchmarkDeep(b *testing.B) {
+	type node struct {
+		Name string
+		Kids []*node
+	}
+	var build func(depth int) *node
+	build = func(depth int) *node {
+		n := &node{Name: "node"}
+		if depth > 0 {
+			for i := 0; i < 4; i++ {
+				n.Kids = append(n.Kids, build(depth-1))
+			}
+		}
+		return n
+	}
+	f := func(string, ...any) (int, error) { return 0, nil }
+	x, y := build(6), build(6)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		diff.Each(f, x, y)
+	}
+}
@@ -0,0 +1,57 @@
+package diff
+
+// A Differ compares values using a fixed set of options.
+// The options are applied once, when the Differ is made,
+// rather than on every comparison, so a Differ is cheaper
+// than the package-level functions for making many
+// comparisons with the same options.
+//
+// A Differ is safe for concurrent use.
+type Differ struct {
+	config config // read-only after New
+}
+
+// New returns a Differ that compares values using
+// the default options and then opt.
+func New(opt ...Option) *Differ {
+	return &Differ{config: newConfig(opt...)}
+}
+
+// Each compares values a and b, calling f for each difference it finds.
+// It is like the package-level function Each.
+func (df *Differ) Each(f func(format string, arg ...any) (int, error), a, b any) {
+	c := df.config
+	c.sink = func(format string, arg ...any) { f(format, arg...) }
+	newDifferConfig(c).each(a, b)
+}
+
+// Compare compares values a and b and returns
+// the differences it finds, in the order Each would
+// report them. It returns nil if a and b are equal.
+// If the comparison runs past its Timeout, the last
+// Change says so.
+// Output options, such as EmitFull, have no effect.
+func (df *Differ) Compare(a, b any) []Change {
+	var found []Change
+	c := df.config
+	c.change = func(ch Change) {
+		found = append(found, ch)
+	}
+	newDifferConfig(c).each(a, b)
+	return found
+}
+
+// Equal reports whether a and b are equal.
+// It stops comparing at the first difference.
+func (df *Differ) Equal(a, b any) bool {
+	equal := true
+	var d *differ
+	c := df.config
+	c.change = func(Change) {
+		equal = false
+		d.stop = true
+	}
+	d = newDifferConfig(c)
+	d.each(a, b)
+	return equal
+}
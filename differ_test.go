@@ -0,0 +1,61 @@
+package diff_test
+
+import (
+	"sync"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestDiffer(t *testing.T) {
+	type T struct {
+		A int
+		B []string
+	}
+	d := diff.New(diff.PathJQ)
+	a := T{1, []string{"x"}}
+	b := T{2, []string{"y"}}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	d.Each(gotp.Printf, a, b)
+	want := ".A: 1 != 2\n.B[0]: \"x\" != \"y\"\n"
+	if got != want {
+		t.Errorf("Each = %q, want %q", got, want)
+	}
+
+	changes := d.Compare(a, b)
+	if len(changes) != 2 || changes[0].String() != ".A: 1 != 2" {
+		t.Errorf("Compare = %v, want 2 changes starting with .A", changes)
+	}
+	if c := d.Compare(a, a); c != nil {
+		t.Errorf("Compare(a, a) = %v, want nil", c)
+	}
+
+	if d.Equal(a, b) {
+		t.Errorf("Equal(a, b) = true, want false")
+	}
+	if !d.Equal(a, a) {
+		t.Errorf("Equal(a, a) = false, want true")
+	}
+}
+
+func TestDifferConcurrent(t *testing.T) {
+	d := diff.New(diff.EqualNaN)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if !d.Equal([]float64{NaN, float64(i)}, []float64{NaN, float64(i)}) {
+					t.Errorf("Equal = false, want true")
+				}
+				if n := len(d.Compare(i, j)); (n == 0) != (i == j) {
+					t.Errorf("Compare(%d, %d) has %d changes", i, j, n)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,59 @@
+package diff
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pkg/diff/myers"
+)
+
+// Distance returns the number of leaf-level edits needed
+// to turn a into b: each changed leaf value (such as a number
+// or string), each added or removed map entry, and each added
+// or removed slice element counts as one edit.
+// Distance returns 0 if and only if Each would find no
+// differences between a and b.
+//
+// The conditions for equality can be adjusted by supplying
+// Option values, the same as for Each.
+func Distance(a, b any, opt ...Option) int {
+	d := newDiffer(func() {}, func(string, ...any) {}, opt...)
+	e := d.newCounter(nil, true)
+	av, bv := rootValue(a), rootValue(b)
+	d.walk(e, av, bv, true, true)
+	return e.n
+}
+
+// countEdits counts the edits needed to turn slice av into bv,
+// which have different lengths. It aligns equal elements,
+// then counts the differences between the elements that
+// replace one another, plus one for each element inserted
+// or deleted.
+func (d *differ) countEdits(e *countEmitter, av, bv reflect.Value) {
+	pair := &valuePair{d: d, a: av, b: bv}
+	for _, ed := range merge(myers.Diff(context.Background(), pair)) {
+		na, nb := ed.a1-ed.a0, ed.b1-ed.b0
+		m := na
+		if nb < m {
+			m = nb
+		}
+		for i := 0; i < m; i++ {
+			d.walk(e.sub(av.Type(), indexStep(ed.a0+i)), av.Index(ed.a0+i), bv.Index(ed.b0+i), true, false)
+		}
+		e.counter().n += na + nb - 2*m
+	}
+}
+
+// A valuePair is a pair of lists whose elements
+// are compared using the rules of a differ.
+type valuePair struct {
+	d    *differ
+	a, b reflect.Value
+}
+
+func (ab *valuePair) LenA() int { return ab.a.Len() }
+func (ab *valuePair) LenB() int { return ab.b.Len() }
+
+func (ab *valuePair) Equal(ai, bi int) bool {
+	return ab.d.equalAt(indexStep(ai), ab.a.Index(ai), ab.b.Index(bi))
+}
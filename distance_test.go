@@ -0,0 +1,37 @@
+package diff_test
+
+import (
+	"fmt"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestDistance(t *testing.T) {
+	type T struct {
+		A int
+		B string
+	}
+	cases := []struct {
+		a, b any
+		want int
+	}{
+		{1, 1, 0},
+		{1, 2, 1},
+		{T{1, "x"}, T{2, "y"}, 2},
+		{"one two three four", "one 2 three 4", 1},
+		{map[int]int{1: 1, 2: 2}, map[int]int{2: 3, 4: 4}, 3},
+		{[]int{1, 2, 3}, []int{1, 2, 3, 4, 5}, 2},
+		{[]int{1, 2, 3}, []int{0, 2}, 2},
+		{[]T{{1, "x"}, {2, "y"}}, []T{{1, "x"}, {3, "z"}, {4, "w"}}, 3},
+		{nil, 1, 1},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprint(tt), func(t *testing.T) {
+			got := diff.Distance(tt.a, tt.b)
+			if got != tt.want {
+				t.Errorf("Distance(%v, %v) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
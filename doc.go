@@ -22,5 +22,36 @@ Here are some common usage examples:
 Use Option values to change how it works if the default
 behavior isn't what you need.
 
+Struct fields can also be annotated with a "diff" tag to
+change how they are compared. The tag value is a
+comma-separated list of these options:
+
+  ignorezero  don't compare the field if either side is zero
+  approx=x    for a float field, treat values within x as equal;
+              x must be a number, not negative or NaN
+  delta=d     for a time.Time or time.Duration field,
+              treat values within duration d (such as 1s)
+              as equal
+  name=s      use s for the field in paths, in place of
+              its Go name or JSON name
+  redact      compare the field, but show [REDACTED]
+              in place of its value in output
+
+A tag of "-" excludes the field from the comparison:
+
+  type User struct {
+      ID      int       `diff:"name=user_id"`
+      Name    string
+      Updated time.Time `diff:"-"`
+      Token   string    `diff:"ignorezero"`
+      Score   float64   `diff:"approx=1e-6"`
+      Created time.Time `diff:"delta=1s"`
+      Secret  string    `diff:"redact"`
+  }
+
+Unknown tag options are ignored, since other packages
+use the diff tag too, and so are options whose values
+are bad or don't suit the type of the field.
+
 */
 package diff
@@ -0,0 +1,60 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dot returns the lines of a DOT graph of the values
+// in s, for EmitDOT. There is a node for each value,
+// with an edge to each value inside it.
+// Leaf nodes show their values, and nodes at or below
+// a difference are drawn in red.
+func (s *sideTable) dot(c config) []string {
+	rows := mergeRows(sideNodes(c, s.a), sideNodes(c, s.b))
+	ids := map[string]int{}
+	lines := []string{
+		"digraph diff {",
+		"\tnode [shape=box, fontname=monospace];",
+	}
+	for i, r := range rows {
+		ids[r.path] = i
+		label := r.label
+		if r.leaf {
+			label += "\n" + r.dotValue()
+		}
+		attr := ""
+		if s.differs(r.path) {
+			attr = ", color=red, fontcolor=red"
+		}
+		lines = append(lines, fmt.Sprintf("\tn%d [label=%s%s];", i, dotQuote(label), attr))
+	}
+	for i, r := range rows {
+		if p, ok := ids[r.parent]; ok && r.path != r.parent {
+			lines = append(lines, fmt.Sprintf("\tn%d -> n%d;", p, i))
+		}
+	}
+	return append(lines, "}")
+}
+
+// dotValue returns the value of leaf row r
+// as shown in its node.
+func (r sideRow) dotValue() string {
+	switch {
+	case !r.haveB:
+		return "(removed) " + r.a
+	case !r.haveA:
+		return "(added) " + r.b
+	case r.a != r.b:
+		return r.a + " != " + r.b
+	}
+	return r.a
+}
+
+// dotQuote returns s as a DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
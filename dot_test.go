@@ -0,0 +1,66 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestDOT(t *testing.T) {
+	type T struct {
+		Name string
+		ID   int
+		Tags map[string]string
+	}
+	a := T{Name: "x", ID: 1, Tags: map[string]string{"k": "v"}}
+	b := T{Name: "y", ID: 1, Tags: map[string]string{"n": "w"}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitDOT)
+	want := "digraph diff {\n" +
+		"\tnode [shape=box, fontname=monospace];\n" +
+		"\tn0 [label=\"diff_test.T\"];\n" +
+		"\tn1 [label=\".Name\\n\\\"x\\\" != \\\"y\\\"\", color=red, fontcolor=red];\n" +
+		"\tn2 [label=\".ID\\n1\"];\n" +
+		"\tn3 [label=\".Tags\"];\n" +
+		"\tn4 [label=\"[\\\"k\\\"]\\n(removed) \\\"v\\\"\", color=red, fontcolor=red];\n" +
+		"\tn5 [label=\"[\\\"n\\\"]\\n(added) \\\"w\\\"\", color=red, fontcolor=red];\n" +
+		"\tn0 -> n1;\n" +
+		"\tn0 -> n2;\n" +
+		"\tn0 -> n3;\n" +
+		"\tn3 -> n4;\n" +
+		"\tn3 -> n5;\n" +
+		"}\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, a, diff.EmitDOT)
+	if got != "" {
+		t.Errorf("equal: got %q, want empty", got)
+	}
+}
+
+func TestDOTNested(t *testing.T) {
+	type Item struct{ N int }
+	type T struct{ P *Item }
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, T{}, T{&Item{3}}, diff.EmitDOT, diff.PathJQ)
+	want := "digraph diff {\n" +
+		"\tnode [shape=box, fontname=monospace];\n" +
+		"\tn0 [label=\"diff_test.T\"];\n" +
+		"\tn1 [label=\".P\\nnil != {N:3}\", color=red, fontcolor=red];\n" +
+		"\tn2 [label=\".N\\n(added) 3\", color=red, fontcolor=red];\n" +
+		"\tn0 -> n1;\n" +
+		"\tn1 -> n2;\n" +
+		"}\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
@@ -0,0 +1,78 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var reflectError = reflect.TypeOf((*error)(nil)).Elem()
+
+// asError returns the error held in v, if v is a non-nil
+// value of a type that implements error.
+func asError(v reflect.Value) (error, bool) {
+	if !v.IsValid() || !v.Type().Implements(reflectError) || !v.CanInterface() || isNil(v) {
+		return nil, false
+	}
+	err, ok := v.Interface().(error)
+	return err, ok
+}
+
+// errorChain returns err followed by the errors it wraps,
+// in the order errors.Is visits them: depth first, following
+// Unwrap() error or, for an error that wraps several,
+// such as one made by fmt.Errorf with more than one %w,
+// Unwrap() []error.
+func errorChain(err error) (chain []error) {
+	if err == nil {
+		return nil
+	}
+	chain = append(chain, err)
+	switch x := err.(type) {
+	case interface{ Unwrap() error }:
+		chain = append(chain, errorChain(x.Unwrap())...)
+	case interface{ Unwrap() []error }:
+		for _, err := range x.Unwrap() {
+			chain = append(chain, errorChain(err)...)
+		}
+	}
+	return chain
+}
+
+// walkErrorChain compares error chains a and b level by level,
+// by type and message, emitting a difference for each level
+// that doesn't match.
+func (d *differ) walkErrorChain(e emitfer, av, bv reflect.Value, a, b error) {
+	d.config.helper()
+	ac, bc := errorChain(a), errorChain(b)
+	n := len(ac)
+	if len(bc) > n {
+		n = len(bc)
+	}
+	for i := 0; i < n; i++ {
+		ae, be := chainLink(ac, i), chainLink(bc, i)
+		if ae != nil && be != nil && reflect.TypeOf(ae) == reflect.TypeOf(be) && ae.Error() == be.Error() {
+			continue
+		}
+		e.emitf(av, bv, "(unwrap %d) %v != %v", i, errorLink{ae}, errorLink{be})
+	}
+}
+
+func chainLink(chain []error, i int) error {
+	if i < len(chain) {
+		return chain[i]
+	}
+	return nil
+}
+
+// An errorLink formats one error in a chain
+// as its type and message.
+type errorLink struct{ err error }
+
+func (l errorLink) Format(f fmt.State, verb rune) {
+	if l.err == nil {
+		fmt.Fprint(f, "(end of chain)")
+		return
+	}
+	writeType(f, reflect.TypeOf(l.err))
+	fmt.Fprintf(f, "(%q)", l.err.Error())
+}
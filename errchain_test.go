@@ -0,0 +1,58 @@
+package diff_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestErrorChains(t *testing.T) {
+	type T struct{ Err error }
+	cases := []struct {
+		name string
+		a, b any
+		want string
+	}{
+		{"equal", T{fmt.Errorf("read: %w", io.EOF)}, T{fmt.Errorf("read: %w", io.EOF)}, ""},
+		{"wrapped", T{fmt.Errorf("read: %w", io.EOF)}, T{io.EOF},
+			"diff_test.T.Err: (unwrap 0) *fmt.wrapError(\"read: EOF\") != *errors.errorString(\"EOF\")\n" +
+				"diff_test.T.Err: (unwrap 1) *errors.errorString(\"EOF\") != (end of chain)\n"},
+		{"inner", fmt.Errorf("read: %w", io.EOF), fmt.Errorf("read: %w", errors.New("EOF!")),
+			"(unwrap 0) *fmt.wrapError(\"read: EOF\") != *fmt.wrapError(\"read: EOF!\")\n" +
+				"(unwrap 1) *errors.errorString(\"EOF\") != *errors.errorString(\"EOF!\")\n"},
+		{"nil", T{nil}, T{io.EOF}, "diff_test.T.Err: nil != &errors.errorString{s:\"EOF\"}\n"},
+		{"multiple", multiError{io.EOF, io.ErrUnexpectedEOF}, multiError{io.EOF, io.ErrClosedPipe},
+			"(unwrap 0) diff_test.multiError(\"EOF; unexpected EOF\") != diff_test.multiError(\"EOF; io: read/write on closed pipe\")\n" +
+				"(unwrap 2) *errors.errorString(\"unexpected EOF\") != *errors.errorString(\"io: read/write on closed pipe\")\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, diff.ErrorChains(true))
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+// A multiError wraps several errors,
+// like the errors made by errors.Join.
+type multiError []error
+
+func (m multiError) Error() string {
+	var s []string
+	for _, err := range m {
+		s = append(s, err.Error())
+	}
+	return strings.Join(s, "; ")
+}
+
+func (m multiError) Unwrap() []error { return m }
@@ -60,3 +60,19 @@ func ExampleTest() {
 func makeDialer() *net.Dialer {
 	return &net.Dialer{Timeout: 5 * time.Second}
 }
+
+func ExampleSprint() {
+	type Item struct {
+		Name string
+		Tags map[string]int
+	}
+	fmt.Println(diff.Sprint(&Item{"a", map[string]int{"x": 1, "yyy": 2}}))
+	// Output:
+	// &diff_test.Item{
+	//     Name: "a",
+	//     Tags: {
+	//         "x":   1,
+	//         "yyy": 2,
+	//     },
+	// }
+}
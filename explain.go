@@ -0,0 +1,153 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Explain describes the configuration that results from
+// applying the default options and then opt, one setting
+// per line, for example:
+//
+//	output: EmitAuto
+//	paths: PathGo
+//	transforms: time.Time
+//	formats: time.Time
+//	EqualFuncs: false
+//	...
+//
+// It is meant for debugging: to check whether an option
+// took effect after merging it with the defaults and
+// with any options given after it.
+// The format of the result may change.
+func Explain(opt ...Option) string {
+	c := newConfig(opt...)
+	var b strings.Builder
+	p := func(name string, v any) { fmt.Fprintf(&b, "%s: %v\n", name, v) }
+
+	output := [...]string{
+		auto:          "EmitAuto",
+		pathOnly:      "EmitPathOnly",
+		full:          "EmitFull",
+		columns:       "EmitColumns",
+		tree:          "EmitTree",
+		sideBySide:    "EmitSideBySide",
+		dotGraph:      "EmitDOT",
+		jsonLines:     "EmitJSONLines",
+		sarif:         "EmitSARIF",
+		githubActions: "EmitGitHubActions",
+		tap:           "EmitTAP",
+	}[c.level]
+	if c.template != nil {
+		output = fmt.Sprintf("Template(%q)", c.template.Name())
+	}
+	p("output", output)
+	p("paths", [...]string{pathGo: "PathGo", pathJQ: "PathJQ", pathPointer: "PathJSONPointer"}[c.pathStyle])
+	p("transforms", typeList(c.xform))
+	p("formats", typeList(c.format))
+	p("CompareKind", kindList(c.kindEq))
+	p("At", scopeList(c.scopes))
+	p("Unordered", typeList(c.unordered))
+	p("SliceSet", typeList(c.sliceSets))
+	p("NormalizeStrings", fmt.Sprintf("%d funcs", len(c.normalize)))
+	p("NormalizeLineEndings", c.crlf)
+	p("EqualFuncs", c.equalFuncs)
+	p("EqualTypedNil", c.equalTypedNil)
+	p("ErrorChains", c.errorChains)
+	p("EqualLocks", c.equalLocks)
+	p("Headers", c.headers)
+	p("IgnoreHeaders", nameList(c.ignoreHeaders))
+	p("QueryValues", c.queryValues)
+	p("DurationTolerance", c.durationTolerance)
+	p("BinaryMarshal", c.binaryMarshal)
+	p("Vector", vectorName(c.vector))
+	p("UintptrHandles", c.uintptrHandles)
+	p("ChanMetadata", c.chanMetadata)
+	p("SortFields", c.sortFields)
+	p("IgnoreZeroTime", c.ignoreZeroTime)
+	p("MapSets", c.mapSets)
+	p("NormalizeKeys", typeList(c.normKeys))
+	p("PointerKeys", c.pointerKeys)
+	p("FullValues", c.fullValues)
+	p("PointerIDs", c.pointerIDs)
+	p("EmitEqual", c.emitEqual)
+	p("CollapseEqual", c.collapse)
+	p("ElemContext", c.elemContext)
+	p("AggregateRepeats", c.aggregate)
+	p("ContainerSummary", c.containerSummary)
+	p("Similarity", c.similarity)
+	p("MaxDiffs", c.maxDiffs)
+	p("MaxContainerDiffs", c.maxContainerDiffs)
+	p("MaxBytes", c.maxBytes)
+	p("TextContext", c.textContext)
+	p("Width", c.width)
+	p("FullWidth", c.fullWidth)
+	p("MaxTypeLen", c.display.maxTypeLen)
+	p("Redact", nameList(c.display.redact))
+	p("Color", [...]string{colorAuto: "auto", colorNever: "false", colorAlways: "true"}[c.color])
+	p("Prefix", fmt.Sprintf("%q", c.prefix))
+	p("Location", c.location)
+	p("Logger", fmt.Sprintf("%T", c.output))
+	return b.String()
+}
+
+// vectorName returns the option that set vt,
+// with its tolerance, or "none".
+func vectorName(vt *vectorTol) string {
+	if vt == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%s(%g)", vt.option(), vt.tol)
+}
+
+// scopeList returns the paths of scopes, sorted, as a
+// comma-separated list, or "none" if there are none.
+func scopeList(scopes []scope) string {
+	names := map[string]bool{}
+	for _, sc := range scopes {
+		names[strings.TrimPrefix(strings.Join(sc.path, ""), ".")] = true
+	}
+	return nameList(names)
+}
+
+// kindList returns the keys of m, sorted, as a
+// comma-separated list, or "none" if m is empty.
+func kindList[V any](m map[reflect.Kind]V) string {
+	names := map[string]bool{}
+	for k := range m {
+		names[k.String()] = true
+	}
+	return nameList(names)
+}
+
+// nameList returns the keys of m, sorted, as a
+// comma-separated list, or "none" if m is empty.
+func nameList(m map[string]bool) string {
+	if len(m) == 0 {
+		return "none"
+	}
+	var a []string
+	for name := range m {
+		a = append(a, name)
+	}
+	sort.Strings(a)
+	return strings.Join(a, ", ")
+}
+
+// typeList returns the keys of m, sorted, as a
+// comma-separated list, or "none" if m is empty.
+func typeList[V any](m map[reflect.Type]V) string {
+	var a []string
+	for t := range m {
+		var b strings.Builder
+		writeType(&b, t)
+		a = append(a, b.String())
+	}
+	if len(a) == 0 {
+		return "none"
+	}
+	sort.Strings(a)
+	return strings.Join(a, ", ")
+}
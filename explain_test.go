@@ -0,0 +1,45 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"kr.dev/diff"
+)
+
+func TestExplain(t *testing.T) {
+	got := diff.Explain()
+	for _, want := range []string{
+		"output: EmitAuto\n",
+		"paths: PathGo\n",
+		"transforms: time.Time\n",
+		"formats: time.Time\n",
+		"EqualFuncs: false\n",
+		"Logger: *log.Logger\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Explain() missing %q; got:\n%s", want, got)
+		}
+	}
+
+	got = diff.Explain(
+		diff.Picky,
+		diff.PathJQ,
+		diff.EqualFuncs(true),
+		diff.Unordered[[]string](),
+		diff.Transform(func(d time.Duration) any { return d.Round(time.Second) }),
+	)
+	for _, want := range []string{
+		"output: EmitFull\n",
+		"paths: PathJQ\n",
+		"transforms: time.Duration\n",
+		"formats: none\n",
+		"Unordered: []string\n",
+		"EqualFuncs: true\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Explain(...) missing %q; got:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"bytes"
+	"reflect"
+)
+
+var (
+	reflectInt        = reflect.TypeOf(0)
+	reflectJSONObject = reflect.TypeOf(map[string]any(nil))
+	reflectJSONArray  = reflect.TypeOf([]any(nil))
+)
+
+// fastEqual reports whether av and bv are equal values of
+// a common type, such as string or map[string]any, checked
+// without the machinery of walk. It reports false if they
+// differ, or if it can't tell, leaving walk to find out.
+//
+// It relies on equal values of these types being equal
+// under every option, so it is off for the options that
+// report something about equal values or how they
+// were compared.
+func (d *differ) fastEqual(av, bv reflect.Value) bool {
+	c := &d.config
+	if c.emitEqual || c.trace != nil || c.aliasing || c.pathScoped || len(c.normKeys) > 0 {
+		return false
+	}
+	if !av.IsValid() || !bv.IsValid() || av.Type() != bv.Type() {
+		return false
+	}
+	switch av.Type() {
+	case reflectString:
+		return av.String() == bv.String()
+	case reflectInt:
+		return av.Int() == bv.Int()
+	case reflectBool:
+		return av.Bool() == bv.Bool()
+	case reflectBytes:
+		return av.IsNil() == bv.IsNil() && bytes.Equal(av.Bytes(), bv.Bytes())
+	case reflectAny, reflectJSONObject, reflectJSONArray:
+		if !av.CanInterface() || !bv.CanInterface() {
+			return false
+		}
+		return anyEqual(av.Interface(), bv.Interface())
+	}
+	return false
+}
+
+// anyEqual reports whether a and b are equal values of
+// the types found in decoded JSON, or int, or []byte.
+// It reports false if they differ, or if either is
+// of some other type.
+func anyEqual(a, b any) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	case float64:
+		b, ok := b.(float64)
+		return ok && a == b
+	case int:
+		b, ok := b.(int)
+		return ok && a == b
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	case []byte:
+		b, ok := b.([]byte)
+		return ok && (a == nil) == (b == nil) && bytes.Equal(a, b)
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || (a == nil) != (b == nil) || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !anyEqual(av, bv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || (a == nil) != (b == nil) || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !anyEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
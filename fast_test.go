@@ -0,0 +1,75 @@
+package diff_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestFastPaths(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b any
+		opt  []diff.Option
+		want string
+	}{
+		{"equal", map[string]any{"a": []any{1.0, "x", true, nil}}, map[string]any{"a": []any{1.0, "x", true, nil}}, nil, ""},
+		{"nested", map[string]any{"a": []any{1.0, "x"}}, map[string]any{"a": []any{1.0, "y"}}, nil,
+			"map[string]any[\"a\"][1]: \"x\" != \"y\"\n"},
+		{"nil", map[string]any{"a": []any(nil)}, map[string]any{"a": []any{}}, nil,
+			"map[string]any[\"a\"]: []any(nil) != []any{}\n"},
+		{"nan", []any{math.NaN()}, []any{math.NaN()}, nil, "[]any[0]: float64(NaN) != float64(NaN)\n"},
+		{"equalnan", []any{math.NaN()}, []any{math.NaN()}, []diff.Option{diff.EqualNaN}, ""},
+		{"bytes", []byte(nil), []byte{}, nil, "[]uint8(nil) != []uint8{}\n"},
+		{"emitequal", []any{"x"}, []any{"x"}, []diff.Option{diff.EmitEqual(true)}, "[]any[0]: = \"x\"\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, tt.a, tt.b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func benchJSON(n int) any {
+	var items []any
+	for i := 0; i < n; i++ {
+		items = append(items, map[string]any{
+			"id":     float64(i),
+			"name":   fmt.Sprintf("item %d", i),
+			"active": i%2 == 0,
+			"tags":   []any{"a", "b", "c"},
+		})
+	}
+	buf, _ := json.Marshal(map[string]any{"items": items})
+	var v any
+	json.Unmarshal(buf, &v)
+	return v
+}
+
+func BenchmarkJSON(b *testing.B) {
+	f := func(string, ...any) (int, error) { return 0, nil }
+	x, y := benchJSON(1000), benchJSON(1000)
+	b.Run("equal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			diff.Each(f, x, y)
+		}
+	})
+	z := benchJSON(1000)
+	z.(map[string]any)["items"].([]any)[500].(map[string]any)["name"] = "other"
+	b.Run("unequal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			diff.Each(f, x, z)
+		}
+	})
+}
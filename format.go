@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
+	"sync"
 	"text/tabwriter"
 	"unsafe"
 
@@ -14,32 +16,59 @@ const tab = "\u00a0\u00a0\u00a0\u00a0" // U+00A0 NO-BREAK SPACE
 
 var reflectAny = reflect.TypeOf((*any)(nil)).Elem()
 
-func formatShort(v reflect.Value, wantType bool) fmt.Formatter {
+func formatShort(disp *display, v reflect.Value, wantType bool) fmt.Formatter {
 	return &formatter{
+		disp:       disp,
 		root:       v,
 		wantType:   wantType,
 		full:       false,
 		allowDepth: 2,
 		seen:       map[visit]bool{},
+		tab:        tab,
 	}
 }
 
-func formatFull(v reflect.Value) fmt.Formatter {
+func formatFull(disp *display, v reflect.Value) fmt.Formatter {
 	return &formatter{
+		disp:       disp,
 		root:       v,
 		wantType:   true,
 		full:       true,
 		allowDepth: 1e8,
 		seen:       map[visit]bool{},
+		tab:        tab,
 	}
 }
 
 type formatter struct {
+	disp       *display // may be nil
 	root       reflect.Value
+	path       *Path  // location of root, if known, for Display
+	steps      []step // from root to the value being written
 	wantType   bool
 	full       bool
 	allowDepth int
 	seen       map[visit]bool
+	tab        string      // indentation for each level, in full form
+	ids        *pointerIDs // for PointerIDs, or nil
+}
+
+// pointerIDs numbers the pointers written in the
+// full values of one comparison, for PointerIDs.
+type pointerIDs struct{ m map[visit]int }
+
+// id returns the number for pointer vis,
+// giving it the next number the first time.
+func (p *pointerIDs) id(vis visit) int {
+	n, ok := p.m[vis]
+	if !ok {
+		if p.m == nil {
+			p.m = map[visit]int{}
+		}
+		n = len(p.m) + 1
+		p.m[vis] = n
+	}
+	return n
 }
 
 func (f *formatter) Format(fs fmt.State, verb rune) {
@@ -55,6 +84,9 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		io.WriteString(w, "nil") // untyped nil
 		return
 	}
+	if f.display(w, v) {
+		return
+	}
 	t := v.Type()
 
 	// Check for cycles.
@@ -65,16 +97,34 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		}
 		vis := visit{unsafe.Pointer(v.Pointer()), t}
 		if f.seen[vis] {
+			if t.Kind() == reflect.Ptr && f.ids != nil {
+				f.writePointerID(w, v)
+				return
+			}
 			io.WriteString(w, "...")
 			return
 		}
 		f.seen[vis] = true
 	}
 
+	if isAtomic(t) {
+		if x, ok := atomicLoad(v); ok {
+			if wantType {
+				f.writeType(w, t)
+				io.WriteString(w, "(")
+			}
+			f.writeTo(w, x, false, depth)
+			if wantType {
+				io.WriteString(w, ")")
+			}
+			return
+		}
+	}
+
 	switch t.Kind() {
 	case reflect.Array:
 		if wantType {
-			writeType(w, t)
+			f.writeType(w, t)
 		}
 		if depth >= f.allowDepth && t.Len() > 0 {
 			io.WriteString(w, "{...}")
@@ -83,9 +133,9 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		io.WriteString(w, "{")
 		if f.full && t.Len() > 1 {
 			io.WriteString(w, "\n")
-			ww := indent.New(w, tab)
+			ww := indent.New(w, f.tab)
 			for i := 0; i < t.Len(); i++ {
-				f.writeTo(ww, v.Index(i), false, depth+1)
+				f.writeAt(ww, indexStep(i), v.Index(i), false, depth+1)
 				io.WriteString(ww, ",\n")
 			}
 		} else {
@@ -94,13 +144,13 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 					io.WriteString(w, ", ...")
 					break
 				}
-				f.writeTo(w, v.Index(i), false, depth+1)
+				f.writeAt(w, indexStep(i), v.Index(i), false, depth+1)
 			}
 		}
 		io.WriteString(w, "}")
 	case reflect.Struct:
 		if wantType {
-			writeType(w, t)
+			f.writeType(w, t)
 		}
 		if depth >= f.allowDepth && t.NumField() > 0 {
 			io.WriteString(w, "{...}")
@@ -110,11 +160,11 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		if f.full && t.NumField() > 1 {
 			io.WriteString(w, "\n")
 			tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
-			ww := indent.New(tw, tab)
+			ww := indent.New(tw, f.tab)
 			for i := 0; i < t.NumField(); i++ {
 				io.WriteString(ww, t.Field(i).Name)
 				io.WriteString(ww, ":\t")
-				f.writeTo(ww, v.Field(i), false, depth+1)
+				f.writeField(ww, v, i, depth+1)
 				io.WriteString(ww, ",\n")
 			}
 			tw.Flush()
@@ -126,13 +176,13 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 				}
 				io.WriteString(w, t.Field(i).Name)
 				io.WriteString(w, ":")
-				f.writeTo(w, v.Field(i), false, depth+1)
+				f.writeField(w, v, i, depth+1)
 			}
 		}
 		io.WriteString(w, "}")
 	case reflect.Func:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType)
+			f.writeTypedNil(w, t, wantType)
 			break
 		}
 		fmt.Fprintf(w, "%v {...}", t)
@@ -140,11 +190,11 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		f.writeTo(w, v.Elem(), true, depth)
 	case reflect.Map:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType)
+			f.writeTypedNil(w, t, wantType)
 			break
 		}
 		if wantType {
-			writeType(w, t)
+			f.writeType(w, t)
 		}
 		if depth >= f.allowDepth && v.Len() > 0 {
 			io.WriteString(w, "{...}")
@@ -155,12 +205,12 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		if f.full && v.Len() > 1 {
 			io.WriteString(w, "\n")
 			tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
-			ww := indent.New(tw, tab)
+			ww := indent.New(tw, f.tab)
 			for _, mk := range sortedKeys(v) {
 				mv := v.MapIndex(mk)
-				f.writeTo(ww, mk, false, 0)
+				f.writeKey(ww, mk)
 				io.WriteString(ww, ":\t")
-				f.writeTo(ww, mv, false, depth+1)
+				f.writeAt(ww, keyStep(mk), mv, false, depth+1)
 				io.WriteString(ww, ",\n")
 			}
 			tw.Flush()
@@ -173,16 +223,21 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 				}
 				first = false
 				mv := v.MapIndex(mk)
-				f.writeTo(w, mk, false, 0)
+				f.writeKey(w, mk)
 				io.WriteString(w, ":")
-				f.writeTo(w, mv, false, depth+1)
+				f.writeAt(w, keyStep(mk), mv, false, depth+1)
 			}
 		}
 
 		io.WriteString(w, "}")
 	case reflect.Ptr:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType)
+			f.writeTypedNil(w, t, wantType)
+			break
+		}
+		if f.ids != nil {
+			f.writePointerID(w, v)
+			f.writeTo(w, v.Elem(), false, depth)
 			break
 		}
 		if wantType || t.Elem().Kind() != reflect.Struct {
@@ -196,11 +251,11 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 		f.writeTo(w, v.Elem(), wantType, depth) // note: don't increment depth
 	case reflect.Slice:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType)
+			f.writeTypedNil(w, t, wantType)
 			break
 		}
 		if wantType {
-			writeType(w, t)
+			f.writeType(w, t)
 		}
 		if depth >= f.allowDepth && v.Len() > 0 {
 			io.WriteString(w, "{...}")
@@ -210,9 +265,9 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 
 		if f.full && v.Len() > 1 {
 			io.WriteString(w, "\n")
-			ww := indent.New(w, tab)
+			ww := indent.New(w, f.tab)
 			for i := 0; i < v.Len(); i++ {
-				f.writeTo(ww, v.Index(i), false, depth+1)
+				f.writeAt(ww, indexStep(i), v.Index(i), false, depth+1)
 				io.WriteString(ww, ",\n")
 			}
 		} else {
@@ -224,32 +279,32 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 						break
 					}
 				}
-				f.writeTo(w, v.Index(i), false, depth+1)
+				f.writeAt(w, indexStep(i), v.Index(i), false, depth+1)
 			}
 		}
 		io.WriteString(w, "}")
 	case reflect.Bool:
-		writeSimple(w, "%v", v, wantType && t.PkgPath() != "")
+		f.writeSimple(w, "%v", v, wantType && t.PkgPath() != "")
 	case reflect.Int, reflect.Int8, reflect.Int16,
 		reflect.Int32, reflect.Int64:
-		writeSimple(w, "%v", v, wantType)
+		f.writeSimple(w, "%v", v, wantType)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16,
 		reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		writeSimple(w, "%v", v, wantType)
+		f.writeSimple(w, "%v", v, wantType)
 	case reflect.Float32, reflect.Float64:
-		writeSimple(w, "%v", v, wantType)
+		f.writeSimple(w, "%v", v, wantType)
 	case reflect.Complex64, reflect.Complex128:
-		writeSimple(w, "%v", v, wantType)
+		f.writeSimple(w, "%v", v, wantType)
 	case reflect.String:
 		// TODO(kr): abbreviate
-		writeSimple(w, "%q", v, wantType && t.PkgPath() != "")
+		f.writeSimple(w, "%q", v, wantType && t.PkgPath() != "")
 	case reflect.Chan:
 		if v.IsNil() {
-			writeTypedNil(w, t, wantType)
+			f.writeTypedNil(w, t, wantType)
 			break
 		}
 		io.WriteString(w, "(")
-		writeType(w, t)
+		f.writeType(w, t)
 		io.WriteString(w, ")")
 		fmt.Fprintf(w, "(%p)", unsafe.Pointer(v.Pointer()))
 	case reflect.UnsafePointer:
@@ -259,9 +314,57 @@ func (f *formatter) writeTo(w io.Writer, v reflect.Value, wantType bool, depth i
 	}
 }
 
-func writeSimple(w io.Writer, verb string, v reflect.Value, showType bool) {
+// writeField writes field i of struct v,
+// unless it is to be redacted.
+func (f *formatter) writeField(w io.Writer, v reflect.Value, i, depth int) {
+	t := v.Type()
+	if f.disp.redacted(t, i) {
+		io.WriteString(w, redacted)
+		return
+	}
+	f.writeAt(w, fieldStep(t.Field(i), &structTags(t)[i]), v.Field(i), false, depth)
+}
+
+// writeAt writes v, found at step s from the value
+// being written, keeping track of its path.
+func (f *formatter) writeAt(w io.Writer, s step, v reflect.Value, wantType bool, depth int) {
+	if f.path == nil {
+		f.writeTo(w, v, wantType, depth)
+		return
+	}
+	f.steps = append(f.steps, s)
+	f.writeTo(w, v, wantType, depth)
+	f.steps = f.steps[:len(f.steps)-1]
+}
+
+// writeKey writes map key k.
+// The Display hook doesn't apply to keys.
+func (f *formatter) writeKey(w io.Writer, k reflect.Value) {
+	path := f.path
+	f.path = nil
+	f.writeTo(w, k, false, 0)
+	f.path = path
+}
+
+// display calls the Display hook, if any, for v at
+// the current path, and writes what it returns.
+// It reports whether it wrote anything.
+func (f *formatter) display(w io.Writer, v reflect.Value) bool {
+	if f.path == nil || f.disp == nil || f.disp.hook == nil {
+		return false
+	}
+	p := *f.path
+	p.steps = append(p.steps[:len(p.steps):len(p.steps)], f.steps...)
+	s, ok := f.disp.hook(p, v)
+	if ok {
+		io.WriteString(w, s)
+	}
+	return ok
+}
+
+func (f *formatter) writeSimple(w io.Writer, verb string, v reflect.Value, showType bool) {
 	if showType {
-		writeType(w, v.Type())
+		f.writeType(w, v.Type())
 		io.WriteString(w, "(")
 	}
 	fmt.Fprintf(w, verb, v)
@@ -270,7 +373,15 @@ func writeSimple(w io.Writer, verb string, v reflect.Value, showType bool) {
 	}
 }
 
-func writeTypedNil(w io.Writer, t reflect.Type, showType bool) {
+// writePointerID writes non-nil pointer v as
+// its type and number, for PointerIDs.
+func (f *formatter) writePointerID(w io.Writer, v reflect.Value) {
+	io.WriteString(w, "&")
+	f.writeType(w, v.Type().Elem())
+	fmt.Fprintf(w, "#%d", f.ids.id(visit{unsafe.Pointer(v.Pointer()), v.Type()}))
+}
+
+func (f *formatter) writeTypedNil(w io.Writer, t reflect.Type, showType bool) {
 	// TODO(kr): print type name here sometimes (depending on context)
 	if showType {
 		needParens := false
@@ -281,7 +392,7 @@ func writeTypedNil(w io.Writer, t reflect.Type, showType bool) {
 		if needParens {
 			io.WriteString(w, "(")
 		}
-		writeType(w, t)
+		f.writeType(w, t)
 		if needParens {
 			io.WriteString(w, ")")
 		}
@@ -293,21 +404,103 @@ func writeTypedNil(w io.Writer, t reflect.Type, showType bool) {
 	}
 }
 
+// maxAnonType is the default for MaxTypeLen.
+const maxAnonType = 40
+
+// writeType writes the Go syntax for t,
+// in full, to w.
 func writeType(w io.Writer, t reflect.Type) {
+	writeTypeLimit(w, t, 0)
+}
+
+// writeType writes t to w, in full if f is
+// formatting a full value, and otherwise with long
+// types abbreviated.
+func (f *formatter) writeType(w io.Writer, t reflect.Type) {
+	limit := f.disp.typeLimit()
+	if f.full {
+		limit = 0
+	}
+	writeTypeLimit(w, t, limit)
+}
+
+// typeStrings maps types and limits seen recently
+// to the string written by writeTypeLimit. It holds
+// at most maxTypeStrings entries, and starts over
+// when it is full, so programs that make new types
+// with reflect, or use many limits, don't grow it
+// without bound.
+var typeStrings struct {
+	sync.Mutex
+	m map[typeKey]string
+}
+
+const maxTypeStrings = 4096
+
+type typeKey struct {
+	t     reflect.Type
+	limit int
+}
+
+// writeTypeLimit writes the Go syntax for t to w.
+// If limit is positive, each type inside t, or t itself,
+// whose syntax would be longer than limit bytes is
+// abbreviated: an anonymous struct or interface type
+// as struct{…} or interface{…}, a generic type by
+// leaving out its type arguments, as in List[…],
+// and other types by abbreviating the types in them.
+func writeTypeLimit(w io.Writer, t reflect.Type, limit int) {
+	k := typeKey{t, limit}
+	typeStrings.Lock()
+	s, ok := typeStrings.m[k]
+	typeStrings.Unlock()
+	if !ok {
+		var b strings.Builder
+		buildType(&b, t, limit)
+		s = b.String()
+		typeStrings.Lock()
+		if typeStrings.m == nil || len(typeStrings.m) >= maxTypeStrings {
+			typeStrings.m = make(map[typeKey]string)
+		}
+		typeStrings.m[k] = s
+		typeStrings.Unlock()
+	}
+	io.WriteString(w, s)
+}
+
+// buildType writes t to w for writeTypeLimit.
+func buildType(w io.Writer, t reflect.Type, limit int) {
 	if t == reflectAny {
 		io.WriteString(w, "any")
 		return
 	}
 
 	if name := t.Name(); name != "" {
-		io.WriteString(w, t.String())
+		s := t.String()
+		if i := strings.IndexByte(s, '['); limit > 0 && len(s) > limit && i >= 0 {
+			s = s[:i] + "[…]"
+		}
+		io.WriteString(w, s)
 		return
 	}
 
+	if limit > 0 && (t.Kind() == reflect.Struct || t.Kind() == reflect.Interface) {
+		var b strings.Builder
+		writeTypeLimit(&b, t, 0)
+		if b.Len() > limit && t.Kind() == reflect.Struct {
+			io.WriteString(w, "struct{…}")
+			return
+		}
+		if b.Len() > limit {
+			io.WriteString(w, "interface{…}")
+			return
+		}
+	}
+
 	switch t.Kind() {
 	case reflect.Array:
 		fmt.Fprintf(w, "[%d]", t.Len())
-		writeType(w, t.Elem())
+		writeTypeLimit(w, t.Elem(), limit)
 	case reflect.Struct:
 		io.WriteString(w, "struct{")
 		if t.NumField() > 0 {
@@ -320,7 +513,7 @@ func writeType(w io.Writer, t reflect.Type) {
 			field := t.Field(i)
 			io.WriteString(w, field.Name)
 			io.WriteString(w, " ")
-			writeType(w, field.Type)
+			writeTypeLimit(w, field.Type, limit)
 		}
 		if t.NumField() > 0 {
 			io.WriteString(w, " ")
@@ -328,7 +521,7 @@ func writeType(w io.Writer, t reflect.Type) {
 		io.WriteString(w, "}")
 	case reflect.Func:
 		io.WriteString(w, "func")
-		writeFunc(w, t)
+		writeFunc(w, t, limit)
 	case reflect.Interface:
 		io.WriteString(w, "interface{ ")
 		for i := 0; i < t.NumMethod(); i++ {
@@ -337,20 +530,20 @@ func writeType(w io.Writer, t reflect.Type) {
 			}
 			method := t.Method(i)
 			io.WriteString(w, method.Name)
-			writeFunc(w, method.Type)
+			writeFunc(w, method.Type, limit)
 		}
 		io.WriteString(w, " }")
 	case reflect.Map:
 		io.WriteString(w, "map[")
-		writeType(w, t.Key())
+		writeTypeLimit(w, t.Key(), limit)
 		io.WriteString(w, "]")
-		writeType(w, t.Elem())
+		writeTypeLimit(w, t.Elem(), limit)
 	case reflect.Ptr:
 		io.WriteString(w, "*")
-		writeType(w, t.Elem())
+		writeTypeLimit(w, t.Elem(), limit)
 	case reflect.Slice:
 		io.WriteString(w, "[]")
-		writeType(w, t.Elem())
+		writeTypeLimit(w, t.Elem(), limit)
 	case reflect.Chan:
 		if t.ChanDir() == reflect.RecvDir {
 			io.WriteString(w, "<-")
@@ -360,13 +553,13 @@ func writeType(w io.Writer, t reflect.Type) {
 			io.WriteString(w, "<-")
 		}
 		io.WriteString(w, " ")
-		writeType(w, t.Elem())
+		writeTypeLimit(w, t.Elem(), limit)
 	default:
 		fmt.Fprint(w, t)
 	}
 }
 
-func writeFunc(w io.Writer, f reflect.Type) {
+func writeFunc(w io.Writer, f reflect.Type, limit int) {
 	io.WriteString(w, "(")
 	n := f.NumIn()
 	for i := 0; i < n; i++ {
@@ -375,9 +568,9 @@ func writeFunc(w io.Writer, f reflect.Type) {
 		}
 		if i == n-1 && f.IsVariadic() {
 			io.WriteString(w, "...")
-			writeType(w, f.In(i).Elem())
+			writeTypeLimit(w, f.In(i).Elem(), limit)
 		} else {
-			writeType(w, f.In(i))
+			writeTypeLimit(w, f.In(i), limit)
 		}
 	}
 	io.WriteString(w, ")")
@@ -392,9 +585,73 @@ func writeFunc(w io.Writer, f reflect.Type) {
 		if i > 0 {
 			io.WriteString(w, ", ")
 		}
-		writeType(w, f.Out(i))
+		writeTypeLimit(w, f.Out(i), limit)
 	}
 	if n > 1 {
 		io.WriteString(w, ")")
 	}
 }
+
+// distinctShort returns formatters for a pair of unequal
+// values av and bv, normally in short form.
+// If the short forms look the same, it escalates to
+// more detail: first adding types, then qualifying
+// type names with their package paths, then using
+// the full form, so the output doesn't say "x != x".
+func distinctShort(disp *display, av, bv reflect.Value, wantType bool) (a, b fmt.Formatter) {
+	short := func(v reflect.Value) fmt.Formatter { return formatShort(disp, v, wantType) }
+	typed := func(v reflect.Value) fmt.Formatter { return formatShort(disp, v, true) }
+	full := func(v reflect.Value) fmt.Formatter { return formatFull(disp, v) }
+	// Formatters keep state, so make new ones after comparing.
+	// Most values differ in short form; the longer forms
+	// are formatted only when they don't.
+	if fmt.Sprint(short(av)) != fmt.Sprint(short(bv)) {
+		return short(av), short(bv)
+	}
+	distinct := func(mk func(reflect.Value) fmt.Formatter) bool {
+		return fmt.Sprint(mk(av)) != fmt.Sprint(mk(bv))
+	}
+	switch {
+	case !wantType && distinct(typed):
+		return typed(av), typed(bv)
+	case av.IsValid() && bv.IsValid() && av.Type() != bv.Type():
+		return qualified{typed(av), av.Type()}, qualified{typed(bv), bv.Type()}
+	case distinct(full):
+		return full(av), full(bv)
+	case av.Kind() == reflect.Func:
+		return short(av), note{short(bv), "non-nil funcs are never equal; see EqualFuncs"}
+	}
+	return short(av), short(bv)
+}
+
+// A qualified formats a value followed by
+// its type, qualified with its package path.
+type qualified struct {
+	fmt.Formatter
+	t reflect.Type
+}
+
+func (q qualified) Format(f fmt.State, verb rune) {
+	q.Formatter.Format(f, verb)
+	fmt.Fprintf(f, " (type %s)", qualifiedName(q.t))
+}
+
+// qualifiedName returns the name of t, qualified with
+// its full package path if it has one.
+func qualifiedName(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+// A note formats a value followed by an explanation.
+type note struct {
+	fmt.Formatter
+	text string
+}
+
+func (n note) Format(f fmt.State, verb rune) {
+	n.Formatter.Format(f, verb)
+	fmt.Fprintf(f, " (%s)", n.text)
+}
@@ -33,6 +33,8 @@ func TestWriteShortUnknownContext(t *testing.T) {
 		{[]int{0}, []int{1}, `[]int[0]: 0 != 1`},
 		{[]any{0}, []any{1}, `[]any[0]: int(0) != int(1)`},
 		{[]any{(*int)(nil)}, []any{ptr(1)}, `[]any[0]: (*int)(nil) != &int(1)`},
+		{[]longAnon{{Age: 1}}, []longAnon{{Age: 2}}, `[]struct{…}[0].Age: 1 != 2`},
+		{[]any{longAnon{}}, []any{nil}, `[]any[0]: struct{…}{Name:"", ...} != nil`},
 	}
 
 	for i, tt := range cases {
@@ -73,12 +75,12 @@ func TestWriteShortSpecial(t *testing.T) {
 	for i, tt := range cases {
 		t.Run(fmt.Sprint(i, ":", tt), func(t *testing.T) {
 			rv := reflect.ValueOf(tt.v)
-			got := fmt.Sprint(formatShort(rv, true))
+			got := fmt.Sprint(formatShort(nil, rv, true))
 			t.Logf("got: %s", got)
 			for _, want := range tt.want {
 				i := strings.Index(got, want)
 				if i < 0 {
-					t.Fatalf("formatShort(%#v) remaining: %#q, want %#q", tt.v, got, want)
+					t.Fatalf("formatShort(nil, %#v) remaining: %#q, want %#q", tt.v, got, want)
 				}
 				got = got[i+len(want):]
 			}
@@ -228,10 +230,10 @@ func TestWriteShort(t *testing.T) {
 	for i, tt := range cases {
 		t.Run(fmt.Sprint(i, ":", tt), func(t *testing.T) {
 			rv := reflect.ValueOf(tt.v)
-			got := fmt.Sprint(formatShort(rv, true))
+			got := fmt.Sprint(formatShort(nil, rv, true))
 			t.Logf("got: %s", got)
 			if got != tt.want {
-				t.Errorf("formatShort(%#v) = %#q, want %#q", tt.v, got, tt.want)
+				t.Errorf("formatShort(nil, %#v) = %#q, want %#q", tt.v, got, tt.want)
 			}
 		})
 	}
@@ -293,9 +295,9 @@ func TestWriteFull(t *testing.T) {
 	for i, tt := range cases {
 		t.Run(fmt.Sprint(i, ":", tt), func(t *testing.T) {
 			rv := reflect.ValueOf(tt.v)
-			got := fmt.Sprint(formatFull(rv))
+			got := fmt.Sprint(formatFull(nil, rv))
 			if got != tt.want {
-				t.Errorf("bad formatFull(%#v)", tt.v)
+				t.Errorf("bad formatFull(nil, %#v)", tt.v)
 				t.Logf("got:\n%s", got)
 				t.Logf("want:\n%s", tt.want)
 			}
@@ -314,7 +316,7 @@ func TestWriteCycle(t *testing.T) {
 	v2.P = v1
 
 	rv := reflect.ValueOf(v1)
-	got := fmt.Sprint(formatFull(rv))
+	got := fmt.Sprint(formatFull(nil, rv))
 
 	const want = tab + "&diff.T{\n" +
 		tab + tab + "N: 1,\n" +
@@ -325,12 +327,63 @@ func TestWriteCycle(t *testing.T) {
 		tab + "}"
 
 	if got != want {
-		t.Errorf("bad formatFull(%#v)", v1)
+		t.Errorf("bad formatFull(nil, %#v)", v1)
 		t.Logf("got:\n%s", got)
 		t.Logf("want:\n%s", want)
 	}
 }
 
+func TestWritePointerIDs(t *testing.T) {
+	type T struct {
+		N int
+		P *T
+	}
+
+	v2 := &T{N: 2, P: nil}
+	v1 := &T{N: 1, P: v2}
+	v2.P = v1
+
+	ids := new(pointerIDs)
+	f := formatFull(nil, reflect.ValueOf(v1)).(*formatter)
+	f.ids = ids
+	got := fmt.Sprint(f)
+	const want = tab + "&diff.T#1{\n" +
+		tab + tab + "N: 1,\n" +
+		tab + tab + "P: &diff.T#2{\n" +
+		tab + tab + tab + "N: 2,\n" +
+		tab + tab + tab + "P: &diff.T#1,\n" +
+		tab + tab + "},\n" +
+		tab + "}"
+	if got != want {
+		t.Errorf("bad formatFull(nil, %#v) with pointer IDs", v1)
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	// The numbers carry over to the next value written.
+	f = formatFull(nil, reflect.ValueOf([]*T{v2, {N: 3}})).(*formatter)
+	f.ids = ids
+	got = fmt.Sprint(f)
+	const want2 = tab + "[]*diff.T{\n" +
+		tab + tab + "&diff.T#2{\n" +
+		tab + tab + tab + "N: 2,\n" +
+		tab + tab + tab + "P: &diff.T#1{\n" +
+		tab + tab + tab + tab + "N: 1,\n" +
+		tab + tab + tab + tab + "P: &diff.T#2,\n" +
+		tab + tab + tab + "},\n" +
+		tab + tab + "},\n" +
+		tab + tab + "&diff.T#3{\n" +
+		tab + tab + tab + "N: 3,\n" +
+		tab + tab + tab + "P: nil,\n" +
+		tab + tab + "},\n" +
+		tab + "}"
+	if got != want2 {
+		t.Errorf("bad second formatFull with pointer IDs")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want2)
+	}
+}
+
 func TestWriteType(t *testing.T) {
 	type T struct{}
 	testWriteType[any](t, "any")
@@ -368,6 +421,34 @@ func TestWriteType(t *testing.T) {
 	testWriteType[unsafe.Pointer](t, "unsafe.Pointer")
 }
 
+type longAnon = struct {
+	Name  string
+	Email string
+	Age   int
+}
+
+func TestWriteTypeLimit(t *testing.T) {
+	cases := []struct {
+		t    reflect.Type
+		want string
+	}{
+		{reflect.TypeOf(struct{ V any }{}), "struct{ V any }"},
+		{reflect.TypeOf(longAnon{}), "struct{…}"},
+		{reflect.TypeOf(map[string]*longAnon{}), "map[string]*struct{…}"},
+		{reflect.TypeOf((*interface {
+			Read([]byte) (int, error)
+			Close() error
+		})(nil)).Elem(), "interface{…}"},
+	}
+	for _, tt := range cases {
+		var buf bytes.Buffer
+		writeTypeLimit(&buf, tt.t, maxAnonType)
+		if got := buf.String(); got != tt.want {
+			t.Errorf("writeTypeLimit(%v) = %#q, want %#q", tt.t, got, tt.want)
+		}
+	}
+}
+
 func testWriteType[T any](t *testing.T, want string) {
 	t.Helper()
 	rt := reflect.TypeOf((*T)(nil)).Elem()
@@ -383,3 +464,33 @@ func testWriteType[T any](t *testing.T, want string) {
 func ptr[T any](v T) *T {
 	return &v
 }
+
+func TestTypeStringsBounded(t *testing.T) {
+	typ := reflect.TypeOf(struct{ A, B int }{})
+	for limit := 1; limit <= 2*maxTypeStrings; limit++ {
+		var buf bytes.Buffer
+		writeTypeLimit(&buf, typ, limit)
+	}
+	typeStrings.Lock()
+	n := len(typeStrings.m)
+	typeStrings.Unlock()
+	if n > maxTypeStrings {
+		t.Errorf("typeStrings has %d entries, want at most %d", n, maxTypeStrings)
+	}
+	var buf bytes.Buffer
+	writeTypeLimit(&buf, typ, maxAnonType)
+	if got, want := buf.String(), "struct{ A int; B int }"; got != want {
+		t.Errorf("writeTypeLimit = %#q, want %#q", got, want)
+	}
+}
+
+func BenchmarkWriteType(b *testing.B) {
+	t := reflect.TypeOf(map[string][]struct {
+		F func(int, ...bool) (any, error)
+		I interface{ M(string) }
+	}{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		writeType(io.Discard, t)
+	}
+}
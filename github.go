@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// writeGitHub writes a difference as an error
+// workflow command, for EmitGitHubActions.
+func (e *printEmitter) writeGitHub(text string) {
+	var props []string
+	if l := e.config.location; l.file != "" {
+		props = append(props, "file="+githubProperty(l.file))
+		if l.line > 0 {
+			props = append(props, "line="+strconv.Itoa(l.line))
+		}
+	}
+	if p := e.pathString(); p != "" {
+		props = append(props, "title="+githubProperty(p))
+	}
+	e.write(true, "%s\n", githubCommand("error", props, text))
+}
+
+// githubCommand returns a workflow command
+// with the given name, properties, and message.
+func githubCommand(name string, props []string, msg string) string {
+	s := "::" + name
+	if len(props) > 0 {
+		s += " " + strings.Join(props, ",")
+	}
+	return s + "::" + githubMessage(msg)
+}
+
+// githubMessage escapes s for the message of a workflow command,
+// so it stays on one line.
+func githubMessage(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// githubProperty escapes s for a property value
+// of a workflow command.
+func githubProperty(s string) string {
+	s = githubMessage(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+// callerLocation returns the location of the innermost
+// call on the stack from outside this module, other than
+// from its tests, for EmitGitHubActions. The file is relative
+// to $GITHUB_WORKSPACE, the root of the checkout, if it's
+// inside it.
+func callerLocation() location {
+	pc := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		f, more := frames.Next()
+		if !inModule(f.Function) && f.File != "" {
+			file := f.File
+			if ws := os.Getenv("GITHUB_WORKSPACE"); ws != "" {
+				if rel, err := filepath.Rel(ws, file); err == nil && !strings.HasPrefix(rel, "..") {
+					file = filepath.ToSlash(rel)
+				}
+			}
+			return location{file, f.Line}
+		}
+		if !more {
+			return location{}
+		}
+	}
+}
+
+// inModule reports whether the function named fn,
+// as in runtime.Frame, is in this module and not in a test.
+func inModule(fn string) bool {
+	pkg := fn
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		if j := strings.Index(pkg[i:], "."); j >= 0 {
+			pkg = pkg[:i+j]
+		}
+	} else if j := strings.Index(pkg, "."); j >= 0 {
+		pkg = pkg[:j]
+	}
+	if strings.HasSuffix(pkg, "_test") {
+		return false
+	}
+	return pkg == "kr.dev/diff" || strings.HasPrefix(pkg, "kr.dev/diff/")
+}
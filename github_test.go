@@ -0,0 +1,51 @@
+package diff_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestGitHubActions(t *testing.T) {
+	type T struct {
+		Name string
+		Note string
+	}
+	a := T{Name: "x", Note: "50%\noff"}
+	b := T{Name: "y", Note: "none"}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, a, b,
+		diff.EmitGitHubActions, diff.Location("api/user_test.go", 42))
+	want := `::error file=api/user_test.go,line=42,title=diff_test.T.Name::"x" != "y"` + "\n" +
+		`::error file=api/user_test.go,line=42,title=diff_test.T.Note::"50%25\noff" != "none"` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestGitHubActionsCaller(t *testing.T) {
+	_, file, line, _ := runtime.Caller(0)
+	t.Setenv("GITHUB_WORKSPACE", filepath.Dir(file))
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, 1, 2, diff.EmitGitHubActions)
+	want := fmt.Sprintf("::error file=github_test.go,line=%d::int(1) != int(2)\n", line+3)
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGitHubActionsTruncated(t *testing.T) {
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, []int{0, 0}, []int{1, 1},
+		diff.EmitGitHubActions, diff.Location("a_test.go", 0), diff.MaxDiffs(1))
+	want := "::error file=a_test.go,title=[]int[0]::0 != 1\n" +
+		"::warning::output truncated, 1 more differences\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
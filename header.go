@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"net/textproto"
+	"reflect"
+)
+
+// orderedHeaders lists the header fields whose values
+// are compared in order by Headers. Each value is a hop
+// or a coding applied in turn, so a change in order
+// changes the meaning.
+var orderedHeaders = map[string]bool{
+	"Content-Encoding":  true,
+	"Forwarded":         true,
+	"Transfer-Encoding": true,
+	"Via":               true,
+	"X-Forwarded-For":   true,
+}
+
+// isHeaderType reports whether t is http.Header
+// or textproto.MIMEHeader.
+// It checks by name, so this package
+// doesn't need to import net/http.
+func isHeaderType(t reflect.Type) bool {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String || t.Elem() != reflectStrings {
+		return false
+	}
+	return t.PkgPath() == "net/http" && t.Name() == "Header" ||
+		t.PkgPath() == "net/textproto" && t.Name() == "MIMEHeader"
+}
+
+var reflectStrings = reflect.TypeOf([]string(nil))
+
+// canonicalHeader returns a copy of header v, of type t,
+// with its keys in canonical form, the values of keys
+// that differ only in case merged, and keys with no
+// values or in ignore removed.
+func canonicalHeader(t reflect.Type, v reflect.Value, ignore map[string]bool) reflect.Value {
+	m := map[string][]string{}
+	iter := v.MapRange()
+	for iter.Next() {
+		k := textproto.CanonicalMIMEHeaderKey(iter.Key().String())
+		if ignore[k] {
+			continue
+		}
+		m[k] = append(m[k], iter.Value().Interface().([]string)...)
+	}
+	h := reflect.MakeMapWithSize(t, len(m))
+	for k, vals := range m {
+		if len(vals) > 0 {
+			h.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), reflect.ValueOf(vals))
+		}
+	}
+	return h
+}
+
+// walkHeader compares headers av and bv of type t
+// by field name, ignoring case, and by the values of
+// each field, ignoring their order except for
+// the fields in orderedHeaders.
+func (d *differ) walkHeader(e emitfer, t reflect.Type, av, bv reflect.Value) {
+	d.config.helper()
+	ah := canonicalHeader(t, av, d.config.ignoreHeaders)
+	bh := canonicalHeader(t, bv, d.config.ignoreHeaders)
+	for _, k := range sortedKeys(ah, bh) {
+		esub := e.sub(t, keyStep(k))
+		a, b := ah.MapIndex(k), bh.MapIndex(k)
+		switch {
+		case !b.IsValid():
+			esub.emitf(a, b, "(removed)")
+		case !a.IsValid():
+			esub.emitf(a, b, "(added) %v", formatShort(&d.config.display, b, false))
+		case orderedHeaders[k.String()]:
+			d.walk(esub, a, b, true, false)
+		default:
+			d.walkUnordered(esub, reflectStrings, a, b)
+		}
+	}
+}
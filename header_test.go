@@ -0,0 +1,46 @@
+package diff_test
+
+import (
+	"net/http"
+	"net/textproto"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestHeaders(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b any
+		opt  []diff.Option
+		want string
+	}{
+		{"case", http.Header{"content-type": {"text/plain"}}, http.Header{"Content-Type": {"text/plain"}}, nil, ""},
+		{"merge", http.Header{"Vary": {"Accept"}, "vary": {"Cookie"}}, http.Header{"Vary": {"Cookie", "Accept"}}, nil, ""},
+		{"empty", http.Header{"X-A": {}}, http.Header{}, nil, ""},
+		{"mime", textproto.MIMEHeader{"x-a": {"1"}}, textproto.MIMEHeader{"X-A": {"1"}}, nil, ""},
+		{"value", http.Header{"X-A": {"1", "2"}}, http.Header{"X-A": {"2", "3"}}, nil,
+			"http.Header[\"X-A\"][0→1]: \"1\" != \"3\"\n"},
+		{"ordered", http.Header{"Via": {"a", "b"}}, http.Header{"Via": {"b", "a"}}, nil,
+			"http.Header[\"Via\"][0]: \"a\" != \"b\"\n" +
+				"http.Header[\"Via\"][1]: \"b\" != \"a\"\n"},
+		{"added", http.Header{}, http.Header{"x-a": {"1"}}, nil,
+			"http.Header[\"X-A\"]: (added) {\"1\"}\n"},
+		{"ignore", http.Header{"Date": {"1"}, "X-A": {"1"}}, http.Header{"date": {"2"}}, []diff.Option{diff.IgnoreHeaders("date", "X-a")}, ""},
+		{"off", http.Header{"x-a": {"1"}}, http.Header{"X-A": {"1"}}, []diff.Option{diff.Headers(false)},
+			"http.Header[\"X-A\"]: (added) {\"1\"}\n" +
+				"http.Header[\"x-a\"]: (removed)\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package httpdiff compares HTTP responses recorded
+// in tests with the responses they are meant to be.
+package httpdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+// A Response describes the response a handler
+// is meant to write.
+type Response struct {
+	// Code is the status code.
+	// Zero means any code.
+	Code int
+
+	// Header holds the header fields to check.
+	// Other fields in the recorded response are ignored.
+	Header http.Header
+
+	// Body is the body. For a JSON response, it is compared
+	// with the recorded body after both are decoded, so key
+	// order and spacing don't matter. It can be JSON text,
+	// as a string or []byte, or a value to encode as JSON,
+	// such as a struct. For any other response, it must be
+	// a string or []byte, and is compared as text.
+	// Nil means any body.
+	Body any
+}
+
+// TestResponse compares the response recorded in rec
+// with want, calling t.Errorf for each difference.
+// A recorded response is JSON if its Content-Type is
+// application/json, or ends in +json.
+// Differences are labeled "status", "header",
+// or "body"; those in a JSON body have paths
+// in jq syntax (see diff.PathJQ).
+// The options in opt apply to the header and body.
+func TestResponse(t testing.TB, rec *httptest.ResponseRecorder, want Response, opt ...diff.Option) {
+	t.Helper()
+	if want.Code != 0 && rec.Code != want.Code {
+		t.Errorf("status: %d != %d", rec.Code, want.Code)
+	}
+	if len(want.Header) > 0 {
+		got := http.Header{}
+		for k := range want.Header {
+			if v := rec.Header().Values(k); len(v) > 0 {
+				got[k] = v
+			}
+		}
+		diff.Test(t, t.Errorf, got, want.Header, diff.Prefix("header: "), diff.OptionList(opt...))
+	}
+	if want.Body == nil {
+		return
+	}
+	if !isJSON(rec.Header().Get("Content-Type")) {
+		w, ok := text(want.Body)
+		if !ok {
+			t.Errorf("body: want %T, not string or []byte, for %q response", want.Body, rec.Header().Get("Content-Type"))
+			return
+		}
+		diff.Test(t, t.Errorf, rec.Body.String(), w, diff.Prefix("body: "), diff.OptionList(opt...))
+		return
+	}
+	var got, w any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Errorf("body: recorded body is not valid JSON: %v", err)
+		return
+	}
+	if err := decodeWant(want.Body, &w); err != nil {
+		t.Errorf("body: want: %v", err)
+		return
+	}
+	diff.Test(t, t.Errorf, got, w, diff.Prefix("body: "), diff.PathJQ, diff.OptionList(opt...))
+}
+
+// isJSON reports whether contentType is a JSON media type.
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
+
+// text returns body as a string, if it is text.
+func text(body any) (string, bool) {
+	switch b := body.(type) {
+	case string:
+		return b, true
+	case []byte:
+		return string(b), true
+	}
+	return "", false
+}
+
+// decodeWant stores in v the JSON value described by body,
+// which is JSON text or a value to encode.
+func decodeWant(body any, v *any) error {
+	b, ok := text(body)
+	if !ok {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		b = string(buf)
+	}
+	if err := json.Unmarshal([]byte(b), v); err != nil {
+		return fmt.Errorf("not valid JSON: %v", err)
+	}
+	return nil
+}
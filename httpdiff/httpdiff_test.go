@@ -0,0 +1,73 @@
+package httpdiff_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"kr.dev/diff/httpdiff"
+)
+
+// recordT records the errors reported by TestResponse.
+type recordT struct {
+	testing.TB
+	errors string
+}
+
+func (t *recordT) Helper() {}
+func (t *recordT) Errorf(format string, arg ...any) {
+	t.errors += strings.TrimSuffix(fmt.Sprintf(format, arg...), "\n") + "\n"
+}
+
+func record(code int, contentType, body string, header ...string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", contentType)
+	for i := 0; i < len(header); i += 2 {
+		rec.Header().Add(header[i], header[i+1])
+	}
+	rec.WriteHeader(code)
+	fmt.Fprint(rec, body)
+	return rec
+}
+
+func TestResponse(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	cases := []struct {
+		name string
+		rec  *httptest.ResponseRecorder
+		want httpdiff.Response
+		errs string
+	}{
+		{"json", record(200, "application/json; charset=utf-8", `{"name": "a", "id": 1}`),
+			httpdiff.Response{Code: 200, Body: Item{1, "a"}}, ""},
+		{"json text", record(200, "application/problem+json", `{"id":1}`),
+			httpdiff.Response{Body: `{"id": 2}`},
+			"body: .id: float64(1) != float64(2)\n"},
+		{"status", record(404, "text/plain", "not found\n"),
+			httpdiff.Response{Code: 200, Body: "not found\n"},
+			"status: 404 != 200\n"},
+		{"text", record(200, "text/plain", "hello"),
+			httpdiff.Response{Body: "goodbye"},
+			"body: \"hello\" != \"goodbye\"\n"},
+		{"header", record(200, "text/plain", "", "x-id", "1", "Vary", "Cookie", "Vary", "Accept"),
+			httpdiff.Response{Header: http.Header{"Vary": {"Accept", "Cookie"}, "X-Id": {"2"}}},
+			"header: http.Header[\"X-Id\"][0]: \"1\" != \"2\"\n"},
+		{"bad json", record(200, "application/json", `{`),
+			httpdiff.Response{Body: Item{}},
+			"body: recorded body is not valid JSON: unexpected end of JSON input\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &recordT{TB: t}
+			httpdiff.TestResponse(rt, tt.rec, tt.want)
+			if rt.errors != tt.errs {
+				t.Errorf("errors:\n%s\nwant:\n%s", rt.errors, tt.errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSON parses a and b as JSON documents and compares them,
+// calling f for each difference it finds.
+// The comparison is semantic: object key order and
+// whitespace don't matter, and numbers are compared
+// by value as float64.
+// Paths are written as JSON Pointers (see PathJSONPointer),
+// unless opt selects a different syntax.
+//
+// JSON returns an error, without calling f, if either
+// a or b is not valid JSON.
+func JSON(f func(format string, arg ...any) (int, error), a, b []byte, opt ...Option) error {
+	var av, bv any
+	if err := json.Unmarshal(a, &av); err != nil {
+		return fmt.Errorf("diff: parsing a: %w", err)
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		return fmt.Errorf("diff: parsing b: %w", err)
+	}
+	fdis := func(format string, arg ...any) { f(format, arg...) }
+	d := newDiffer(func() {}, fdis, PathJSONPointer, OptionList(opt...))
+	d.each(av, bv)
+	return nil
+}
@@ -0,0 +1,44 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestJSON(t *testing.T) {
+	a := `{"name": "x", "tags": ["a", "b"], "n": 1, "m": {"k/1": true}}`
+	b := `{
+		"m": {"k/1": false},
+		"n": 1.0,
+		"tags": ["a", "c"],
+		"name": "x",
+		"extra": null
+	}`
+	var got string
+	gotp := (*stringPrinter)(&got)
+	err := diff.JSON(gotp.Printf, []byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `/extra: (added) nil` + "\n" +
+		`/m/k~11: true != false` + "\n" +
+		`/tags/1: "b" != "c"` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestJSONError(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	err := diff.JSON(gotp.Printf, []byte(`{`), []byte(`{}`))
+	if err == nil {
+		t.Errorf("JSON(invalid) = nil, want error")
+	}
+	if got != "" {
+		t.Errorf("JSON(invalid) printed %q, want nothing", got)
+	}
+}
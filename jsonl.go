@@ -0,0 +1,96 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// A jsonLine is one line of EmitJSONLines output.
+type jsonLine struct {
+	Path  string `json:"path"`
+	Kind  string `json:"kind"` // changed, added, removed, truncated, or timeout
+	A     string `json:"a,omitempty"`
+	B     string `json:"b,omitempty"`
+	AType string `json:"aType,omitempty"`
+	BType string `json:"bType,omitempty"`
+	Text  string `json:"text"`
+}
+
+// writeJSONLine writes a difference as a jsonLine,
+// for EmitJSONLines.
+func (e *printEmitter) writeJSONLine(av, bv reflect.Value, format string, arg ...any) {
+	line := jsonLine{
+		Path: e.pathString(),
+		Kind: changeKind(av, bv),
+		Text: fmt.Sprintf(format, arg...),
+	}
+	line.A, line.AType = e.jsonValue(av)
+	line.B, line.BType = e.jsonValue(bv)
+	e.write(true, "%s", encodeJSONLine(line))
+}
+
+// changeKind returns the kind of difference between av and bv:
+// "added" if only bv is valid, "removed" if only av is valid,
+// and otherwise "changed".
+func changeKind(av, bv reflect.Value) string {
+	switch {
+	case !av.IsValid():
+		return "added"
+	case !bv.IsValid():
+		return "removed"
+	}
+	return "changed"
+}
+
+// jsonValue returns the short form of v and its type,
+// or empty strings if v is not valid. For a value from
+// a redacted field, the short form is [REDACTED].
+func (e *printEmitter) jsonValue(v reflect.Value) (s, typ string) {
+	if !v.IsValid() {
+		return "", ""
+	}
+	if rv, ok := asReportValue(v); ok {
+		return rv.text, rv.typ
+	}
+	f := formatShort(&e.config.display, v, false)
+	if e.config.display.hook != nil {
+		locate([]any{f}, e.pathValue())
+	}
+	return fmt.Sprint(f), typeString(v)
+}
+
+// writeSummary writes text, a line about the output
+// as a whole, at the end of the output.
+// For EmitJSONLines, it writes a jsonLine of the given kind.
+// For EmitSARIF, it adds a notification to the log,
+// for EmitGitHubActions, it writes a warning command,
+// and for EmitTAP, it writes a comment.
+func (e *printEmitter) writeSummary(kind, text string) {
+	switch e.config.level {
+	case jsonLines:
+		e.config.sink("%s", encodeJSONLine(jsonLine{Kind: kind, Text: text}))
+		return
+	case sarif:
+		e.out.sarif.notes = append(e.out.sarif.notes, sarifNotification{"warning", sarifMessage{text}})
+		return
+	case githubActions:
+		e.config.sink("%s\n", githubCommand("warning", nil, text))
+		return
+	case tap:
+		e.config.sink("%s# %s\n", e.tapStart(), text)
+		return
+	}
+	e.config.sink("%s\n", text)
+}
+
+// encodeJSONLine returns line as JSON
+// followed by a newline.
+func encodeJSONLine(line jsonLine) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(line) // can't fail
+	return buf.String()
+}
@@ -0,0 +1,58 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestJSONLines(t *testing.T) {
+	type T struct {
+		Name string
+		Tags map[string]string
+	}
+	a := T{Name: "<x>", Tags: map[string]string{"k": "v"}}
+	b := T{Name: "y", Tags: map[string]string{"n": "w"}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitJSONLines)
+	want := `{"path":"diff_test.T.Name","kind":"changed","a":"\"<x>\"","b":"\"y\"","aType":"string","bType":"string","text":"\"<x>\" != \"y\""}` + "\n" +
+		`{"path":"diff_test.T.Tags[\"k\"]","kind":"removed","a":"\"v\"","aType":"string","text":"(removed)"}` + "\n" +
+		`{"path":"diff_test.T.Tags[\"n\"]","kind":"added","b":"\"w\"","bType":"string","text":"(added) \"w\""}` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestJSONLinesTruncated(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, []int{0, 0}, []int{1, 1}, diff.EmitJSONLines, diff.MaxDiffs(1))
+	want := `{"path":"[]int[0]","kind":"changed","a":"0","b":"1","aType":"int","bType":"int","text":"0 != 1"}` + "\n" +
+		`{"path":"","kind":"truncated","text":"output truncated, 1 more differences"}` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestJSONLinesRedact(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string `diff:"redact"`
+		Token    string
+	}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, Login{"u", "secret1", "t1"}, Login{"u", "secret2", "t2"},
+		diff.EmitJSONLines, diff.Redact("Token"))
+	want := `{"path":"diff_test.Login.Password","kind":"changed","a":"[REDACTED]","b":"[REDACTED]","aType":"string","bType":"string","text":"[REDACTED] != [REDACTED]"}` + "\n" +
+		`{"path":"diff_test.Login.Token","kind":"changed","a":"[REDACTED]","b":"[REDACTED]","aType":"string","bType":"string","text":"[REDACTED] != [REDACTED]"}` + "\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
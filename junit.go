@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// junitMaxBytes is the default limit on the size of
+	// the body of a JUnitFailure element.
+	junitMaxBytes = 64 << 10
+
+	// junitMaxMessage is the limit on the size of
+	// the message attribute of a JUnitFailure element.
+	junitMaxMessage = 200
+)
+
+// JUnitFailure compares values a and b and returns their
+// differences as a JUnit XML failure element, for teams that
+// publish JUnit reports from Go tests, for example:
+//
+//	<failure message="T.Name: &quot;x&quot; != &quot;y&quot;" type="kr.dev/diff">T.Name: "x" != "y"
+//	T.Age: 1 != 2
+//	</failure>
+//
+// Its message attribute is the first line of output, and its body
+// is the whole output, limited to 64 KiB by default. Use MaxBytes
+// to set a different limit. The limit applies to the output before
+// it is escaped for XML, so escaping can make the body longer.
+// JUnitFailure returns the empty string if a and b are equal.
+func JUnitFailure(a, b any, opt ...Option) string {
+	var buf strings.Builder
+	d := newDiffer(func() {}, func(format string, arg ...any) {
+		fmt.Fprintf(&buf, format, arg...)
+	}, MaxBytes(junitMaxBytes), OptionList(opt...))
+	d.each(a, b)
+	if buf.Len() == 0 {
+		return ""
+	}
+	out := buf.String()
+	msg, _, _ := strings.Cut(out, "\n")
+	if len(msg) > junitMaxMessage {
+		n := junitMaxMessage
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n--
+		}
+		msg = msg[:n] + "…"
+	}
+	return `<failure message="` + xmlEscape(msg, true) + `" type="kr.dev/diff">` + xmlEscape(out, false) + "</failure>"
+}
+
+// xmlEscape returns s escaped for XML text or,
+// if attr is true, a quoted attribute value.
+// It replaces characters XML doesn't allow,
+// such as most control characters, with U+FFFD.
+func xmlEscape(s string, attr bool) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '"' && attr:
+			b.WriteString("&quot;")
+		case r < ' ' && r != '\t' && r != '\n' && r != '\r',
+			r == 0xFFFE, r == 0xFFFF:
+			b.WriteRune(utf8.RuneError)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
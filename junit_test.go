@@ -0,0 +1,72 @@
+package diff_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestJUnitFailure(t *testing.T) {
+	type T struct {
+		Name string
+		Age  int
+	}
+	got := diff.JUnitFailure(T{"<a&b>", 1}, T{"x\x00", 2})
+	want := `<failure message="diff_test.T.Name: &quot;&lt;a&amp;b&gt;&quot; != &quot;x\x00&quot;" type="kr.dev/diff">` +
+		`diff_test.T.Name: "&lt;a&amp;b&gt;" != "x\x00"` + "\n" +
+		"diff_test.T.Age: 1 != 2\n" +
+		"</failure>"
+	if got != want {
+		t.Errorf("JUnitFailure:\ngot  %s\nwant %s", got, want)
+	}
+	var f struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Body    string `xml:",chardata"`
+	}
+	if err := xml.Unmarshal([]byte(got), &f); err != nil {
+		t.Fatalf("JUnitFailure is not valid XML: %v", err)
+	}
+	if !strings.HasPrefix(f.Body, f.Message+"\n") {
+		t.Errorf("message %q is not the first line of body %q", f.Message, f.Body)
+	}
+}
+
+func TestJUnitFailureEqual(t *testing.T) {
+	if got := diff.JUnitFailure(1, 1); got != "" {
+		t.Errorf("JUnitFailure(1, 1) = %q, want empty", got)
+	}
+}
+
+func TestJUnitFailureLimits(t *testing.T) {
+	a := make([]int, 10000)
+	b := make([]int, 10000)
+	for i := range b {
+		b[i] = 1
+	}
+	got := diff.JUnitFailure(a, b, diff.EmitFull)
+	if len(got) > 70<<10 {
+		t.Errorf("len(JUnitFailure) = %d, want at most about 64 KiB", len(got))
+	}
+	if !strings.Contains(got, "output truncated") {
+		t.Errorf("JUnitFailure does not say it was truncated")
+	}
+	got = diff.JUnitFailure(a, b, diff.MaxBytes(100))
+	if !strings.Contains(got, "output truncated") || len(got) > 400 {
+		t.Errorf("JUnitFailure with MaxBytes(100) = %q", got)
+	}
+
+	long := strings.Repeat("é", 500)
+	got = diff.JUnitFailure(long, "", diff.Format(func(a, b string) string { return a }))
+	var f struct {
+		Message string `xml:"message,attr"`
+	}
+	if err := xml.Unmarshal([]byte(got), &f); err != nil {
+		t.Fatalf("JUnitFailure is not valid XML: %v", err)
+	}
+	if want := strings.Repeat("é", 100) + "…"; f.Message != want {
+		t.Errorf("message = %q, want %q", f.Message, want)
+	}
+}
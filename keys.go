@@ -0,0 +1,29 @@
+package diff
+
+import "reflect"
+
+// normalizeKeys returns a copy of map m, of type t, with each
+// key replaced by the result of normalizer f, for NormalizeKeys.
+// If two keys in m give the same result, it keeps the entry
+// for the first key in sorted order and emits a difference
+// naming both keys, since one entry can't be compared.
+func (d *differ) normalizeKeys(e emitfer, t reflect.Type, f, m reflect.Value, label string) reflect.Value {
+	d.config.helper()
+	if m.IsNil() {
+		return m
+	}
+	n := reflect.MakeMapWithSize(t, m.Len())
+	orig := map[any]reflect.Value{}
+	for _, k := range sortedKeys(m) {
+		nk := reflectApply(f, k)
+		if prev, ok := orig[nk.Interface()]; ok {
+			e.sub(t, keyStep(nk)).emitf(reflect.Value{}, reflect.Value{},
+				"keys %v and %v in %s are the same after normalizing",
+				formatShort(&d.config.display, prev, false), formatShort(&d.config.display, k, false), label)
+			continue
+		}
+		orig[nk.Interface()] = k
+		n.SetMapIndex(nk, m.MapIndex(k))
+	}
+	return n
+}
@@ -0,0 +1,53 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestNormalizeKeys(t *testing.T) {
+	lower := diff.NormalizeKeys(strings.ToLower)
+	trim := diff.NormalizeKeys(strings.TrimSpace)
+	cases := []struct {
+		name string
+		a, b map[string]int
+		opt  []diff.Option
+		want string
+	}{
+		{"equal", map[string]int{"Name": 1, "AGE": 2}, map[string]int{"name": 1, "age": 2}, []diff.Option{lower}, ""},
+		{"value", map[string]int{"Name": 1}, map[string]int{"name": 2}, []diff.Option{lower},
+			"map[string]int[\"name\"]: 1 != 2\n"},
+		{"replaced", map[string]int{" x": 1}, map[string]int{"X": 1}, []diff.Option{lower, trim},
+			"map[string]int[\"X\"]: (added) 1\n" +
+				"map[string]int[\"x\"]: (removed)\n"},
+		{"collision", map[string]int{"A": 1, "a": 2}, map[string]int{"a": 1}, []diff.Option{lower},
+			"map[string]int[\"a\"]: keys \"A\" and \"a\" in a are the same after normalizing\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, tt.a, tt.b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeKeysAny(t *testing.T) {
+	a := map[string]any{"A": 1.0, "a": 1.0}
+	b := map[string]any{"A": 1.0, "a": 1.0}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, a, b, diff.NormalizeKeys(strings.ToLower))
+	want := "map[string]any[\"a\"]: keys \"A\" and \"a\" in a are the same after normalizing\n" +
+		"map[string]any[\"a\"]: keys \"A\" and \"a\" in b are the same after normalizing\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// isSelfUnequal reports whether v is not equal to itself
+// under the == operator, because it is or contains a NaN.
+// Map entries with such keys can't be found with MapIndex.
+func isSelfUnequal(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return math.IsNaN(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return math.IsNaN(real(c)) || math.IsNaN(imag(c))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if isSelfUnequal(v.Index(i)) {
+				return true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if isSelfUnequal(v.Field(i)) {
+				return true
+			}
+		}
+	case reflect.Interface:
+		return !v.IsNil() && isSelfUnequal(v.Elem())
+	}
+	return false
+}
+
+// A mapEntry is a key and value from a map.
+type mapEntry struct{ k, v reflect.Value }
+
+// selfUnequalEntries returns the entries of map m
+// whose keys are not equal to themselves,
+// in order of their formatted values.
+func selfUnequalEntries(m reflect.Value) (a []mapEntry) {
+	iter := m.MapRange()
+	for iter.Next() {
+		if isSelfUnequal(iter.Key()) {
+			a = append(a, mapEntry{iter.Key(), iter.Value()})
+		}
+	}
+	sort.SliceStable(a, func(i, j int) bool {
+		return fmt.Sprint(a[i].v) < fmt.Sprint(a[j].v)
+	})
+	return a
+}
+
+// walkSelfUnequalKeys compares the entries of maps av and bv
+// of type t whose keys are not equal to themselves,
+// such as NaN. These keys are paired using the same rules
+// as other values, so they match only if an option such as
+// EqualNaN makes them equal. Entries with equal values are
+// paired first, then the rest in order.
+// Entries left over are reported as removed or added.
+func (d *differ) walkSelfUnequalKeys(e emitfer, t reflect.Type, av, bv reflect.Value) {
+	d.config.helper()
+	d.walkEntries(e, t, selfUnequalEntries(av), selfUnequalEntries(bv))
+}
+
+// walkEntries compares entries as and bs, from maps of type t,
+// pairing them by key as described for walkSelfUnequalKeys.
+func (d *differ) walkEntries(e emitfer, t reflect.Type, as, bs []mapEntry) {
+	d.config.helper()
+	if len(as) == 0 && len(bs) == 0 {
+		return
+	}
+	used := make([]bool, len(bs))
+	pair := func(a mapEntry, sameValue bool) bool {
+		for j, b := range bs {
+			if used[j] || !d.equal(a.k, b.k) {
+				continue
+			}
+			if sameValue && !d.equalAt(keyStep(a.k), a.v, b.v) {
+				continue
+			}
+			used[j] = true
+			if !sameValue {
+				d.walk(e.sub(t, keyStep(a.k)), a.v, b.v, true, false)
+			}
+			return true
+		}
+		return false
+	}
+	var rest []mapEntry
+	for _, a := range as {
+		if !pair(a, true) {
+			rest = append(rest, a)
+		}
+	}
+	for _, a := range rest {
+		if !pair(a, false) {
+			e.sub(t, keyStep(a.k)).emitf(a.v, reflect.Value{}, "(removed)")
+		}
+	}
+	for j, b := range bs {
+		if !used[j] {
+			e.sub(t, keyStep(b.k)).emitf(reflect.Value{}, b.v, "(added) %v", formatShort(&d.config.display, b.v, false))
+		}
+	}
+}
@@ -2,8 +2,10 @@ package diff
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"math"
+	"net/textproto"
 	"reflect"
 	"time"
 )
@@ -15,6 +17,14 @@ const (
 	auto level = iota
 	pathOnly
 	full
+	columns
+	tree
+	sideBySide
+	dotGraph
+	jsonLines
+	sarif
+	githubActions
+	tap
 )
 
 // Option values can be passed to the Each function to control
@@ -22,6 +32,11 @@ const (
 // and various other things.
 // Options are applied in order from left to right;
 // later options win where there is a conflict.
+//
+// Functions that make options check their arguments
+// and panic if they are invalid, so mistakes show up
+// where the option is made, not during a comparison.
+// The zero Option has no effect.
 type Option struct{ apply func(*config) }
 
 // OptionList combines multiple options into one.
@@ -29,7 +44,9 @@ type Option struct{ apply func(*config) }
 func OptionList(opt ...Option) Option {
 	return Option{func(c *config) {
 		for _, o := range opt {
-			o.apply(c)
+			if o.apply != nil {
+				o.apply(c)
+			}
 		}
 	}}
 }
@@ -42,6 +59,8 @@ var (
 		EmitAuto,
 		TimeEqual,
 		TimeDelta,
+		EqualLocks(true),
+		Headers(true),
 		Logger(log.Default()),
 	)
 	defaultOpt = Default // actual value that cannot be changed
@@ -52,6 +71,8 @@ var (
 		EmitFull,
 		TransformRemove[time.Time](),
 		FormatRemove[time.Time](),
+		EqualLocks(false),
+		Headers(false),
 	)
 )
 
@@ -59,18 +80,233 @@ var (
 	// EmitAuto selects an output format for each difference
 	// based on various heuristics.
 	// It uses registered format functions. See Format.
+	// Each difference is one line (or a unified diff,
+	// for multi-line strings), for example:
+	//
+	//	T.Name: "a" != "b"
+	//	T.Tags["x"]: (removed)
+	//
+	// This is the default.
 	EmitAuto Option = verbosity(auto)
 
 	// EmitPathOnly outputs the path to each difference
 	// in Go notation.
 	// It does not use registered format functions.
+	// Each difference is one line, for example:
+	//
+	//	T.Name
+	//	T.Tags["x"]
 	EmitPathOnly Option = verbosity(pathOnly)
 
 	// EmitFull outputs the path to each difference
 	// and a full representation of both values
 	// at that position, pretty-printed on multiple
-	// lines with indentation.
+	// lines with indentation, for example:
+	//
+	//	T:
+	//	a.Name:
+	//	    "a"
+	//	b.Name:
+	//	    "b"
+	//
+	// The labels a and b are got and want in Test.
 	EmitFull Option = verbosity(full)
+
+	// EmitColumns outputs the same information as EmitAuto,
+	// but holds it back until the comparison is done,
+	// then pads the paths and values into aligned columns,
+	// for example:
+	//
+	//	T.Name:       "a" != "b"
+	//	T.Items[3].N: 1   != 2
+	//	T.Tags["x"]:  (removed)
+	//
+	// This makes long lists of differences easier to scan.
+	EmitColumns Option = verbosity(columns)
+
+	// EmitTree outputs the same information as EmitAuto,
+	// but holds it back until the comparison is done,
+	// then groups the differences under their shared
+	// path prefixes, with indentation, for example:
+	//
+	//	pkg.Order
+	//	    .Items[2]
+	//	        .Price: 5 != 6
+	//	        .Name: "a" != "b"
+	//	    .Owner.Email: "a@example.com" != "b@example.com"
+	//
+	// This makes clusters of related differences
+	// stand out.
+	EmitTree Option = verbosity(tree)
+
+	// EmitSideBySide holds back output until the comparison
+	// is done, then, if there are any differences, shows
+	// the two values in full as two columns, one row for each
+	// value at the bottom of the structure, aligned by path,
+	// for example:
+	//
+	//	                a     b
+	//	pkg.T.Name      "x" | "y"
+	//	pkg.T.ID        1     1
+	//	pkg.T.Tags["k"] "v" <
+	//	pkg.T.Tags["n"]     > "w"
+	//
+	// The mark between the columns is "|" for a row that
+	// differs, "<" or ">" for a row only in a or only in b,
+	// and a space for an equal row.
+	// Values with a transform or format func are shown
+	// as a whole, in one row.
+	EmitSideBySide Option = verbosity(sideBySide)
+
+	// EmitDOT holds back output until the comparison
+	// is done, then, if there are any differences, writes
+	// the two values as one graph in the DOT language
+	// of Graphviz, for example:
+	//
+	//	digraph diff {
+	//		node [shape=box, fontname=monospace];
+	//		n0 [label="pkg.T"];
+	//		n1 [label=".Name\n\"x\" != \"y\"", color=red, fontcolor=red];
+	//		n2 [label=".ID\n1"];
+	//		n0 -> n1;
+	//		n0 -> n2;
+	//	}
+	//
+	// There is a node for each value, with an edge to
+	// each value inside it. Values at the bottom of the
+	// structure are shown in their nodes, and nodes
+	// that differ are red. Render it with a command
+	// such as "dot -Tsvg" to see where large values
+	// diverge.
+	EmitDOT Option = verbosity(dotGraph)
+
+	// EmitJSONLines outputs each difference as a JSON object
+	// on one line, as soon as it's found, for example:
+	//
+	//	{"path":"T.N","kind":"changed","a":"1","b":"2","aType":"int","bType":"int","text":"1 != 2"}
+	//	{"path":"T.M[1]","kind":"removed","a":"2","aType":"int","text":"(removed)"}
+	//
+	// The kind is "changed", "added", or "removed".
+	// The values a and b are in short form, as in EmitAuto,
+	// and are left out, along with their types, if absent.
+	// Lines saying the output was truncated or timed out
+	// are objects too, with kind "truncated" or "timeout".
+	// This suits tools that read a stream of records,
+	// such as jq or a log pipeline.
+	EmitJSONLines Option = verbosity(jsonLines)
+
+	// EmitSARIF outputs the differences as a SARIF 2.1.0 log,
+	// in one JSON document written at the end, if there are
+	// any differences. Each difference is a result of rule
+	// "diff/difference" at level "error", for example:
+	//
+	//	{
+	//	  "ruleId": "diff/difference",
+	//	  "level": "error",
+	//	  "message": {"text": "T.Name: \"x\" != \"y\""},
+	//	  "locations": [{
+	//	    "physicalLocation": {
+	//	      "artifactLocation": {"uri": "api/user_test.go"},
+	//	      "region": {"startLine": 42}
+	//	    },
+	//	    "logicalLocations": [{"fullyQualifiedName": "T.Name"}]
+	//	  }],
+	//	  "properties": {"kind": "changed", "a": "\"x\"", "b": "\"y\""}
+	//	}
+	//
+	// The physical location is the one set by Location,
+	// and is left out if there is none. The properties are
+	// as in EmitJSONLines. Lines saying the output was
+	// truncated or timed out are tool notifications.
+	// This suits CI systems and code review tools
+	// that show SARIF results as annotations.
+	EmitSARIF Option = verbosity(sarif)
+
+	// EmitGitHubActions outputs each difference as a GitHub Actions
+	// error workflow command, as soon as it's found, for example:
+	//
+	//	::error file=api/user_test.go,line=42,title=T.Name::"x" != "y"
+	//
+	// GitHub shows these as annotations on the file and line,
+	// including inline on a pull request. The location is the
+	// one set by Location, if any. Otherwise, it is the innermost
+	// call on the stack from outside this module, such as the
+	// test function that called Each, with the file relative
+	// to $GITHUB_WORKSPACE. The title is the path.
+	// Lines saying the output was truncated or timed out
+	// are warning commands.
+	//
+	// The runner only recognizes a command at the start of
+	// a line of the job's log. Package testing indents the
+	// output of Test, so in a test, use Each with a func that
+	// writes to standard output, such as fmt.Printf.
+	EmitGitHubActions Option = verbosity(githubActions)
+
+	// EmitTAP outputs the differences in the Test Anything
+	// Protocol, version 13. Each difference is a failed test
+	// point, described by its path, with a YAML block giving
+	// the details, for example:
+	//
+	//	TAP version 13
+	//	not ok 1 - T.Name
+	//	  ---
+	//	  message: "\"x\" != \"y\""
+	//	  kind: changed
+	//	  a: "\"x\""
+	//	  b: "\"y\""
+	//	  ...
+	//	1..1
+	//
+	// The kind and values are as in EmitJSONLines.
+	// If there are no differences, the output is a single
+	// passing test point, "ok 1 - equal", so unlike other
+	// output forms, EmitTAP writes output for equal values.
+	// Use it with Each, not Test.
+	// Lines saying the output was truncated or timed out
+	// are TAP comments. The version line and the plan
+	// don't count toward MaxBytes, and the plan counts
+	// only the test points written; if MaxBytes leaves
+	// room for none, the output ends with "Bail out!".
+	// This suits test harnesses that read TAP
+	// from programs in any language.
+	EmitTAP Option = verbosity(tap)
+)
+
+var (
+	// PathGo writes paths in Go notation, starting with
+	// the type of the root value, for example:
+	//
+	//	pkg.T.Items[3].Name
+	//	map[string]int["x"]
+	//
+	// This is the default.
+	PathGo Option = pathSyntax(pathGo)
+
+	// PathJQ writes paths in the syntax of jq,
+	// for example:
+	//
+	//	.items[3].name
+	//	.["content-type"]
+	//
+	// Struct fields are named the same way as in
+	// package encoding/json, using the field's json tag
+	// if it has one.
+	PathJQ Option = pathSyntax(pathJQ)
+
+	// PathJSONPointer writes paths as JSON Pointers
+	// (RFC 6901), for example:
+	//
+	//	/items/3/name
+	//	/content-type
+	//
+	// Struct fields are named the same way as in
+	// package encoding/json, using the field's json tag
+	// if it has one.
+	// A difference in the root value has the empty path.
+	// JSON Pointer can't refer to part of a string or a run
+	// of elements, so those are written after the pointer
+	// in the style of PathJQ, as in /body[0:12].
+	PathJSONPointer Option = pathSyntax(pathPointer)
 )
 
 var (
@@ -83,6 +319,14 @@ var (
 	})
 
 	// EqualNaN causes NaN float64 values to be treated as equal.
+	//
+	// This also applies to NaN map keys. Such keys are never
+	// equal to themselves, so they are always compared
+	// by pairing them up explicitly. Without EqualNaN,
+	// NaN keys in a are reported as removed and NaN keys
+	// in b as added. With EqualNaN, each NaN key in a
+	// is paired with one in b, preferring entries with
+	// equal values.
 	EqualNaN Option = Transform(func(f float64) any {
 		if math.IsNaN(f) {
 			type equalNaN struct{}
@@ -98,6 +342,17 @@ var (
 		bs := b.Format(time.RFC3339Nano)
 		return fmt.Sprintf("%s != %s (%s)", as, bs, b.Sub(a))
 	})
+
+	// DurationDelta outputs two durations with
+	// the difference between them, for example:
+	//
+	//	T.Timeout: 1.5s != 1.502s (Δ2ms)
+	//
+	// Without it, durations are written by their String
+	// method, with no difference.
+	DurationDelta Option = Format(func(a, b time.Duration) string {
+		return fmt.Sprintf("%v != %v (Δ%v)", a, b, b-a)
+	})
 )
 
 // verbosity controls how much detail is produced for each difference found.
@@ -107,6 +362,13 @@ func verbosity(n level) Option {
 	}}
 }
 
+// pathSyntax sets the syntax used to write paths.
+func pathSyntax(s pathStyle) Option {
+	return Option{func(c *config) {
+		c.pathStyle = s
+	}}
+}
+
 // EqualFuncs controls how function values are compared.
 // If true, any two non-nil function values of the same type
 // are treated as equal;
@@ -119,18 +381,564 @@ func EqualFuncs(b bool) Option {
 	}}
 }
 
+// FullValues controls whether each difference reported
+// by EmitAuto is followed by the full representation
+// of both values at that position, pretty-printed
+// the same way as EmitFull, for example:
+//
+//	T.Users["bob"]: (added) {Name:"Bob", ...}
+//	b:
+//	    T.User{
+//	        Name: "Bob",
+//	        Age:  42,
+//	    }
+//
+// A value that doesn't exist, such as the b side of
+// a removed map entry, is left out.
+// This mixes the concise paths of EmitAuto with the
+// detail of EmitFull. It has no effect on other output
+// formats. The default is false.
+func FullValues(b bool) Option {
+	return Option{func(c *config) {
+		c.fullValues = b
+	}}
+}
+
+// EmitEqual controls whether equal values are reported
+// along with the differences. If true, each value at the
+// bottom of the structure that was compared and found
+// equal, such as a number or string, is written marked
+// with "=", for example:
+//
+//	T.Name: = "a"
+//	T.Age: 1 != 2
+//
+// Together with EmitTree, this shows everything that
+// was compared as one annotated tree.
+// Equal values don't count toward MaxDiffs.
+// Values skipped by options, struct tags,
+// or CollapseEqual are not written.
+// It affects EmitAuto, EmitColumns, and EmitTree.
+// The default is false.
+func EmitEqual(b bool) Option {
+	return Option{func(c *config) {
+		c.emitEqual = b
+	}}
+}
+
+// EqualTypedNil controls how nil interface values are compared
+// with interface values holding a typed nil.
+// If true, an interface holding a nil pointer, map, slice,
+// channel, or func is treated as equal to a nil interface,
+// so that, for example, error(nil) and error((*MyErr)(nil))
+// are equal.
+// Otherwise, they are unequal, matching the == operator.
+// This also applies to the arguments a and b themselves.
+// The default is false.
+func EqualTypedNil(b bool) Option {
+	return Option{func(c *config) {
+		c.equalTypedNil = b
+	}}
+}
+
+// ErrorChains controls how non-nil error values are compared.
+// If true, each error is unwrapped repeatedly, as by errors.Is,
+// and the two chains are compared level by level, by dynamic type
+// and message. An error that wraps several errors, with an
+// Unwrap method that returns []error, is followed in its chain
+// by each of them and the errors they wrap, in order.
+// Each level that doesn't match is reported, for example:
+//
+//	T.Err: (unwrap 0) *fmt.wrapError("read: EOF") != *errors.errorString("EOF")
+//	T.Err: (unwrap 1) *errors.errorString("EOF") != (end of chain)
+//
+// Errors whose chains match are treated as equal,
+// even if they differ in other ways.
+// Otherwise, errors are compared like any other values.
+// The default is false.
+func ErrorChains(b bool) Option {
+	return Option{func(c *config) {
+		c.errorChains = b
+	}}
+}
+
+// EqualLocks controls whether values of type sync.Mutex,
+// sync.RWMutex, sync.Once, and sync.WaitGroup are always
+// treated as equal. Their internal state depends on what
+// goroutines happen to be doing, so it is rarely what a
+// comparison means to check.
+// If false, they are compared like any other struct.
+// The default is true.
+func EqualLocks(b bool) Option {
+	return Option{func(c *config) {
+		c.equalLocks = b
+	}}
+}
+
+// Headers controls whether values of type http.Header and
+// textproto.MIMEHeader are compared as headers, the way
+// a server or client would read them: field names are
+// compared in canonical form, ignoring case, and a field
+// with no values is the same as a missing one.
+// The values of a field are compared without regard to
+// their order, except for fields where order carries
+// meaning: Content-Encoding, Forwarded, Transfer-Encoding,
+// Via, and X-Forwarded-For.
+// If false, they are compared like any other map.
+// The default is true.
+func Headers(b bool) Option {
+	return Option{func(c *config) {
+		c.headers = b
+	}}
+}
+
+// IgnoreHeaders leaves out the header fields with the
+// given names, in any case, when comparing headers.
+// This is useful for fields that change on every request,
+// such as Date or X-Request-Id.
+// It has no effect unless Headers is true.
+// IgnoreHeaders may be given more than once to add more names.
+func IgnoreHeaders(names ...string) Option {
+	return Option{func(c *config) {
+		m := map[string]bool{}
+		for name := range c.ignoreHeaders {
+			m[name] = true
+		}
+		for _, name := range names {
+			m[textproto.CanonicalMIMEHeaderKey(name)] = true
+		}
+		c.ignoreHeaders = m
+	}}
+}
+
+// QueryValues controls whether url.Values are compared
+// by the values for each key without regard to their order,
+// and whether the RawQuery field of url.URL is decoded
+// and compared the same way, so that a=1&b=2 is equal
+// to b=2&a=1, and a=%41 to a=A. A query that can't be
+// decoded is compared as a string, for example:
+//
+//	url.URL.RawQuery["b"][0]: "2" != "3"
+//
+// The default is false.
+func QueryValues(b bool) Option {
+	return Option{func(c *config) {
+		c.queryValues = b
+	}}
+}
+
+// IgnoreZeroTime controls whether a zero time.Time matches
+// any other time. If true, a time.Time that is zero on either
+// side is equal to the value on the other side, and so is a
+// *time.Time that is nil or points to a zero time.
+// This is useful for timestamps that are not set yet,
+// such as in a value before and after it is stored.
+// The default is false.
+func IgnoreZeroTime(b bool) Option {
+	return Option{func(c *config) {
+		c.ignoreZeroTime = b
+	}}
+}
+
+// DurationTolerance treats time.Duration values as equal
+// if they are no more than d apart, such as timings
+// measured on a clock. Values further apart are shown
+// as usual. A field can also be given a tolerance
+// with its struct tag; see the package documentation.
+// The default is 0, for exact comparison.
+// DurationTolerance panics if d is negative.
+func DurationTolerance(d time.Duration) Option {
+	if d < 0 {
+		panic("diff: negative DurationTolerance")
+	}
+	return Option{func(c *config) {
+		c.durationTolerance = d
+	}}
+}
+
+// BinaryMarshal controls whether values whose type
+// implements encoding.BinaryMarshaler, or whose pointer
+// type does, are compared by the bytes it returns.
+// Differences are shown the same way as for []byte,
+// for example:
+//
+//	T.Key: binary: "\xff\x02" != "\xff\x03"
+//
+// This is useful for keys, hashes, and other compact types
+// whose fields are private or don't matter.
+// If MarshalBinary returns an error for either value,
+// or a transform func is registered for the type,
+// they are compared as usual.
+// The default is false.
+func BinaryMarshal(b bool) Option {
+	return Option{func(c *config) {
+		c.binaryMarshal = b
+	}}
+}
+
+// VectorMaxAbs compares slices and arrays of floats
+// of the same length as whole vectors: they are equal
+// if no pair of elements is more than tol apart.
+// Otherwise, it reports one difference for the vector,
+// with the largest difference and where it is,
+// rather than one for each element, for example:
+//
+//	T.Weights: max abs 0.5 > 0.01 (worst at [3]: 1 != 1.5)
+//
+// Slices of different lengths are compared as usual.
+// A later VectorRMSE replaces VectorMaxAbs.
+// VectorMaxAbs panics if tol is negative or NaN.
+func VectorMaxAbs(tol float64) Option {
+	return vectorOption(&vectorTol{name: "max abs", tol: tol})
+}
+
+// VectorRMSE is like VectorMaxAbs, but vectors are equal
+// if the root mean square of the differences between their
+// elements is at most tol, for example:
+//
+//	T.Weights: RMSE 0.25 > 0.01 (worst at [3]: 1 != 1.5)
+//
+// This allows for noise spread over many elements.
+// A later VectorMaxAbs replaces VectorRMSE.
+// VectorRMSE panics if tol is negative or NaN.
+func VectorRMSE(tol float64) Option {
+	return vectorOption(&vectorTol{name: "RMSE", tol: tol, rmse: true})
+}
+
+func vectorOption(vt *vectorTol) Option {
+	if !(vt.tol >= 0) {
+		panic("diff: bad " + vt.option() + " tolerance")
+	}
+	return Option{func(c *config) {
+		c.vector = vt
+	}}
+}
+
+// UintptrHandles controls how uintptr values are compared.
+// If true, they are treated as opaque handles, such as
+// OS handles or pointers from cgo, whose values change
+// from run to run: they are equal if both are zero or
+// both are non-zero.
+// To treat all uintptr values as equal, use CompareKind:
+//
+//	diff.CompareKind(reflect.Uintptr, func(a, b reflect.Value) bool {
+//		return true
+//	})
+//
+// The default is false.
+func UintptrHandles(b bool) Option {
+	return Option{func(c *config) {
+		c.uintptrHandles = b
+	}}
+}
+
+// ChanMetadata controls how non-nil channels are compared.
+// Normally, as in reflect.DeepEqual, two channels are equal
+// only if they are the same channel.
+// If ChanMetadata is true, channels are instead compared by
+// their capacity and the number of elements queued in them
+// (their length), for example:
+//
+//	T.Out: cap 0 != 10
+//
+// A nil channel still differs from a non-nil one,
+// and channels of different direction differ in type.
+// The default is false.
+func ChanMetadata(b bool) Option {
+	return Option{func(c *config) {
+		c.chanMetadata = b
+	}}
+}
+
+// SortFields controls the order in which differences
+// in struct fields are reported.
+// Normally they follow the order the fields are declared in.
+// If SortFields is true, they are sorted by field name instead,
+// so output stays the same when fields are reordered.
+// This doesn't change how whole struct values are written.
+// The default is false.
+func SortFields(b bool) Option {
+	return Option{func(c *config) {
+		c.sortFields = b
+	}}
+}
+
+// CollapseEqual summarizes each run of n or more consecutive
+// equal elements in a slice or array that has at least one
+// difference,
+// for example:
+//
+//	[]int[3..97]: (equal, 95 elements)
+//
+// This makes it clear which elements were compared and
+// found equal, rather than leaving gaps between the
+// reported indices.
+// Summaries are only written with EmitAuto.
+// CollapseEqual(0), the default, turns off summaries.
+// CollapseEqual panics if n is negative.
+func CollapseEqual(n int) Option {
+	if n < 0 {
+		panic("diff: negative CollapseEqual")
+	}
+	return Option{func(c *config) {
+		c.collapse = n
+	}}
+}
+
+// ElemContext shows up to n equal elements on either side
+// of each differing element in a slice or array,
+// marked as context,
+// for example:
+//
+//	[]int[4]: (context) 7
+//	[]int[5]: 8 != 9
+//	[]int[6]: (context) 10
+//
+// This makes shifted elements (off-by-one errors)
+// easy to recognize.
+// Context is only written with EmitAuto.
+// ElemContext(0), the default, turns off context.
+// ElemContext panics if n is negative.
+func ElemContext(n int) Option {
+	if n < 0 {
+		panic("diff: negative ElemContext")
+	}
+	return Option{func(c *config) {
+		c.elemContext = n
+	}}
+}
+
+// AggregateRepeats summarizes identical differences
+// that occur in n or more elements of the same slice
+// or array.
+// Instead of one line per element, it writes a single
+// line such as:
+//
+//	pkg.Order.Items[*].Version: 347 elements differ: 1 != 2
+//
+// Summaries appear only in EmitAuto, EmitColumns, and EmitTree
+// output; other forms of output report every element.
+// AggregateRepeats(0), the default, turns off summaries.
+// AggregateRepeats panics if n is negative.
+func AggregateRepeats(n int) Option {
+	if n < 0 {
+		panic("diff: negative AggregateRepeats")
+	}
+	return Option{func(c *config) {
+		c.aggregate = n
+	}}
+}
+
+// ContainerSummary writes a line before the differences inside
+// each map, and each slice or array of the same length, giving
+// its length and how many of its elements were added, removed,
+// and changed, so the shape of a change is clear before its
+// details, for example:
+//
+//	pkg.Config.Env: len 10→12, 3 added, 1 removed, 2 changed
+//	pkg.Config.Env["HOME"]: "/root" != "/home/user"
+//	…
+//
+// Counts of zero are left out. Slices of different lengths
+// already have just one line saying so.
+// The summary is only written with EmitAuto,
+// EmitColumns, and EmitTree.
+// The default is false.
+func ContainerSummary(b bool) Option {
+	return Option{func(c *config) {
+		c.containerSummary = b
+	}}
+}
+
+// Similarity writes a line before the differences inside
+// each map, and each slice or array of the same length,
+// giving the percentage of its elements that are equal,
+// rounded down, and how many of them differ, to help find
+// which parts of a large value changed most, for example:
+//
+//	pkg.Snapshot.Items: 92% equal (2/25 differ)
+//
+// For a map, the elements are the keys in either map.
+// With ContainerSummary, it is added to the same line:
+//
+//	pkg.Snapshot.Items: len 25, 2 changed, 92% equal (2/25 differ)
+//
+// The default is false.
+func Similarity(b bool) Option {
+	return Option{func(c *config) {
+		c.similarity = b
+	}}
+}
+
+// Unordered compares slices of type S without regard
+// to the order of their elements.
+//
+// Elements that are equal are paired up first.
+// The remaining elements are paired greedily,
+// so that elements with the fewest differences
+// between them (as measured by Distance) are
+// compared to each other. Such a pair is reported with
+// both indexes in the path, for example:
+//
+//	[]pkg.Item[2→5].Price: 3 != 4
+//
+// Any elements left over are reported as removed
+// (with their index in a) or added (with their index in b).
+//
+// This is useful for slices of structs with no natural key
+// to sort by. Pairing takes time proportional to the product
+// of the lengths of the two slices.
+func Unordered[S ~[]E, E any]() Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*S)(nil)).Elem()
+		c.unordered[t] = true
+	}}
+}
+
+// SliceSet compares slices of type S as sets of unique
+// elements. Order and duplicate elements are ignored.
+// Only elements present in one slice and not the other
+// are reported, as removed (with their index in a)
+// or added (with their index in b).
+//
+// Unlike MapSets, which applies to every map of a suitable
+// type, SliceSet applies only to type S, so ordered and
+// unordered slices can be mixed in the same value.
+// Comparison takes time proportional to the product
+// of the lengths of the two slices.
+func SliceSet[S ~[]E, E any]() Option {
+	return Option{func(c *config) {
+		t := reflect.TypeOf((*S)(nil)).Elem()
+		c.sliceSets[t] = true
+	}}
+}
+
+// TextContext sets the number of unchanged lines shown
+// around each hunk when two multi-line strings are
+// emitted as a unified diff. The default is 3.
+// TextContext panics if n is negative.
+func TextContext(n int) Option {
+	if n < 0 {
+		panic("diff: negative TextContext")
+	}
+	return Option{func(c *config) {
+		c.textContext = n
+	}}
+}
+
+// NormalizeStrings applies f to strings (and byte slices
+// compared as text) before comparing them.
+// Strings that are equal after normalization are treated
+// as equal, and differences are reported between the
+// normalized forms.
+// For example, f could put strings into Unicode normal
+// form NFC using golang.org/x/text/unicode/norm:
+//
+//	diff.NormalizeStrings(norm.NFC.String)
+//
+// If NormalizeStrings is given more than once,
+// each function is applied in turn.
+// NormalizeStrings panics if f is nil.
+func NormalizeStrings(f func(string) string) Option {
+	if f == nil {
+		panic("diff: nil normalizer")
+	}
+	return Option{func(c *config) {
+		c.normalize = append(c.normalize, f)
+	}}
+}
+
+// NormalizeLineEndings controls whether line endings
+// in strings (and byte slices compared as text) are normalized.
+// If true, each "\r\n" is treated as "\n" when comparing,
+// and also when showing a line-by-line diff, so text written
+// with Windows line endings is equal to the same text
+// written with Unix line endings.
+// Normalization of line endings happens before any
+// functions given to NormalizeStrings are applied.
+// The default is false.
+func NormalizeLineEndings(b bool) Option {
+	return Option{func(c *config) {
+		c.crlf = b
+	}}
+}
+
+// MapSets controls how maps with bool or empty struct values
+// are compared.
+// If true, such maps are treated as sets of keys:
+// a key is a member if it is present with value true
+// (for bool maps) or present at all (for struct{} maps).
+// Only added and removed members are reported, never values.
+// In particular, a nil map and an empty map are equal,
+// and so are map[K]bool{k: false} and map[K]bool{}.
+// If false, they are compared like any other map.
+func MapSets(b bool) Option {
+	return Option{func(c *config) {
+		c.mapSets = b
+	}}
+}
+
+// NormalizeKeys sets a function to apply to the keys
+// of maps with keys of type K before they are matched up,
+// so that keys that differ only cosmetically match,
+// for example:
+//
+//	diff.NormalizeKeys(strings.ToLower)
+//	diff.NormalizeKeys(strings.TrimSpace)
+//
+// Paths show the normalized keys.
+// If two keys in the same map are the same after normalizing,
+// only the first, in sorted order, is compared, and the
+// collision is reported as a difference.
+// A later NormalizeKeys for the same K replaces f.
+// NormalizeKeys panics if f is nil.
+func NormalizeKeys[K comparable](f func(K) K) Option {
+	if f == nil {
+		panic("diff: nil NormalizeKeys func")
+	}
+	return Option{func(c *config) {
+		c.normKeys[reflect.TypeOf((*K)(nil)).Elem()] = reflect.ValueOf(f)
+	}}
+}
+
+// PointerKeys controls how the entries of maps keyed
+// by pointers are matched up.
+// Normally, as with the == operator, a key in a matches
+// a key in b only if they are the same pointer.
+// If PointerKeys is true, keys match if the values they
+// point to are equal, as when both maps were built
+// from scratch. Entries whose keys' values are equal
+// are paired first if their values are equal too,
+// then the rest in order.
+// Entries left over are reported as removed or added.
+// The default is false.
+func PointerKeys(b bool) Option {
+	return Option{func(c *config) {
+		c.pointerKeys = b
+	}}
+}
+
 // ZeroFields transforms a value of struct type T. It makes a copy of its input
 // and sets the specified fields to their zero values.
 //
 // This effectively makes comparison ignore the given fields.
+// ZeroFields panics if T is not a struct type, or if any
+// of the fields doesn't exist or is unexported.
 //
 // See also Transform.
 func ZeroFields[T any](fields ...string) Option {
 	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic("diff: ZeroFields type " + t.String() + " is not a struct")
+	}
 	for _, name := range fields {
-		if _, ok := t.FieldByName(name); !ok {
+		f, ok := t.FieldByName(name)
+		if !ok {
 			panic("diff: field not found: " + name)
 		}
+		if !f.IsExported() {
+			panic("diff: ZeroFields field " + name + " is unexported")
+		}
 	}
 	return Transform(func(v T) any {
 		e := reflect.ValueOf(&v).Elem()
@@ -153,15 +961,99 @@ func ZeroFields[T any](fields ...string) Option {
 // The original, untransformed value is still emitted
 // when a difference is found.
 //
-// See TransformRemove to remove a transform.
+// If T is an interface type, the transform also applies
+// to values of every type that implements T, unless there
+// is a transform for that type itself. For example,
+// Transform[error] can compare all errors by message.
+//
+// Giving more than one transform for the same type T makes
+// a chain: they are applied in order, each to the result of
+// the one before, as long as that result is still a T.
+// For example, a transform for time.Time given after the
+// default TimeEqual receives times already in UTC.
+//
+// See TransformRemove to remove the transforms for a type.
 func Transform[T any](f func(T) any) Option {
+	if f == nil {
+		panic("diff: nil Transform func")
+	}
 	return Option{func(c *config) {
 		t := reflect.TypeOf((*T)(nil)).Elem()
-		c.xform[t] = reflect.ValueOf(f)
+		chain := c.xform[t]
+		c.xform[t] = append(chain[:len(chain):len(chain)], transform{t, func(v any) any {
+			x, _ := v.(T) // v is nil for a nil interface T
+			return f(x)
+		}})
+	}}
+}
+
+// CompareKind sets a function to report whether two values of
+// kind k are equal, for every type of that kind, such as
+// all float64 values, including those of named types.
+// This is useful for a policy that should apply everywhere
+// in a value, for example:
+//
+//	diff.CompareKind(reflect.Float64, func(a, b reflect.Value) bool {
+//		return math.Abs(a.Float()-b.Float()) < 1e-9
+//	})
+//
+// Values that f reports unequal are shown as usual.
+// A transform or format func for a particular type takes
+// precedence over CompareKind.
+// Kind k must be a boolean, numeric, or string kind,
+// and f must not be nil; otherwise CompareKind panics.
+// CompareKind with a kind already set replaces its function.
+func CompareKind(k reflect.Kind, f func(a, b reflect.Value) bool) Option {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+	default:
+		panic("diff: CompareKind of non-basic kind " + k.String())
+	}
+	if f == nil {
+		panic("diff: nil CompareKind func")
+	}
+	return Option{func(c *config) {
+		m := map[reflect.Kind]func(a, b reflect.Value) bool{k: f}
+		for k1, f1 := range c.kindEq {
+			if k1 != k {
+				m[k1] = f1
+			}
+		}
+		c.kindEq = m
 	}}
 }
 
-// TransformRemove removes any transform for type T.
+// At applies opt only to the value at path
+// and the values inside it, on top of the options
+// in effect there. This lets one part of a value
+// be compared differently from the rest, for example:
+//
+//	diff.At("Config.Limits", diff.CompareKind(reflect.Float64, approx))
+//
+// The path is written in Go syntax, as in output,
+// but without the type of the root value:
+// field selectors, indexes, and map keys,
+// such as "Items[2].Price" or `Labels["env"]`.
+// The leading dot is optional.
+// An index of [*] matches any element of a slice or array,
+// or any entry of a map, such as "Items[*].Price".
+// A path in At nested inside another At is relative
+// to the outer path.
+// At panics if path is malformed.
+func At(path string, opt ...Option) Option {
+	sc := scope{path: parseScope(path), opt: OptionList(opt...)}
+	return Option{func(c *config) {
+		c.scopes = append(c.scopes[:len(c.scopes):len(c.scopes)], sc)
+		c.pathScoped = true
+	}}
+}
+
+// TransformRemove removes all transforms for type T.
 // See Transform.
 func TransformRemove[T any]() Option {
 	return Option{func(c *config) {
@@ -173,8 +1065,15 @@ func TransformRemove[T any]() Option {
 // Format customizes the description of the difference
 // between two unequal values a and b.
 //
+// If T is an interface type, the format func also applies
+// to values of every type that implements T, unless there
+// is a format func for that type itself.
+//
 // See FormatRemove to remove a custom format.
 func Format[T any](f func(a, b T) string) Option {
+	if f == nil {
+		panic("diff: nil Format func")
+	}
 	return Option{func(c *config) {
 		t := reflect.TypeOf((*T)(nil)).Elem()
 		c.format[t] = reflect.ValueOf(f)
@@ -190,6 +1089,318 @@ func FormatRemove[T any]() Option {
 	}}
 }
 
+// Aliasing controls whether differences in pointer sharing
+// are reported.
+// Normally, as in reflect.DeepEqual, a pointer, map, or slice
+// reachable by more than one path is compared separately
+// each time it's reached, so a value shared in a is equal
+// to equal copies in b.
+// If Aliasing is true, the comparison also reports a value
+// that is shared in one of a or b but not the other,
+// for example:
+//
+//	T.Y: aliasing differs: shared with T.X in a but not b
+//
+// Here a.X and a.Y point to the same value, but b.X
+// and b.Y point to different values, even if those
+// values are equal. This matters for data structures
+// that intern values or share them until written.
+// The default is false.
+func Aliasing(b bool) Option {
+	return Option{func(c *config) {
+		c.aliasing = b
+	}}
+}
+
+// FailFast controls whether a comparison stops
+// at the first difference.
+// If true, only the first difference found is reported,
+// and the rest of the two values is not compared.
+// This saves time when only equality matters, such as
+// in a check on a hot path. The default is false.
+func FailFast(b bool) Option {
+	return Option{func(c *config) {
+		c.failFast = b
+	}}
+}
+
+// Timeout limits the time spent on a comparison to
+// about d. If the comparison takes longer, it stops,
+// and a line is written at the end saying so, for example:
+//
+//	comparison timed out after 1s and 12 differences
+//
+// Differences found before the timeout are reported
+// as usual. Compare and Stream report the timeout as
+// a final Change with an empty Path and Text such as
+// "comparison timed out after 1s".
+// The time is checked periodically, so a
+// comparison may run somewhat longer than d.
+// Timeout(0), the default, means no limit.
+// Timeout panics if d is negative.
+func Timeout(d time.Duration) Option {
+	if d < 0 {
+		panic("diff: negative Timeout")
+	}
+	return Option{func(c *config) {
+		c.timeout = d
+	}}
+}
+
+// Trace writes a line to w for each value visited
+// during a comparison, saying how it was compared:
+// for example, whether a transform applied, whether
+// a field was skipped because of its struct tag,
+// or which kind of value was compared.
+// This helps to find out why an option doesn't seem
+// to take effect. Comparisons made internally, such as
+// pairing elements for Unordered, are not traced.
+// The format of the trace may change.
+func Trace(w io.Writer) Option {
+	return Option{func(c *config) {
+		c.trace = w
+	}}
+}
+
+// MaxDiffs limits the output to the first n differences.
+// If there are more, a single line is written at the end
+// saying how many were left out.
+// MaxDiffs(0), the default, means no limit.
+// MaxDiffs panics if n is negative.
+func MaxDiffs(n int) Option {
+	if n < 0 {
+		panic("diff: negative MaxDiffs")
+	}
+	return Option{func(c *config) {
+		c.maxDiffs = n
+	}}
+}
+
+// MaxContainerDiffs limits the output for each slice, array,
+// map, or struct to differences in its first n elements,
+// entries, or fields that differ. If more differ, a line
+// is written after them saying how many were left out,
+// for example:
+//
+//	T.Items[0]: 1 != 2
+//	T.Items[1]: 3 != 4
+//	T.Items: …and 120 more in this slice
+//
+// This keeps one value with many differences from
+// crowding out the differences elsewhere.
+// Unlike MaxDiffs, it applies to each value separately.
+// It has no effect on EmitSideBySide or EmitDOT.
+// MaxContainerDiffs(0), the default, means no limit.
+// MaxContainerDiffs panics if n is negative.
+func MaxContainerDiffs(n int) Option {
+	if n < 0 {
+		panic("diff: negative MaxContainerDiffs")
+	}
+	return Option{func(c *config) {
+		c.maxContainerDiffs = n
+	}}
+}
+
+// MaxBytes limits the output to about n bytes.
+// Any difference whose description would go past the limit
+// is left out, and a single line is written at the end
+// saying how many were left out.
+// MaxBytes(0), the default, means no limit.
+// MaxBytes panics if n is negative.
+func MaxBytes(n int) Option {
+	if n < 0 {
+		panic("diff: negative MaxBytes")
+	}
+	return Option{func(c *config) {
+		c.maxBytes = n
+	}}
+}
+
+// MaxTypeLen limits the length of each type written in short
+// form, as in differences and paths, to about n bytes,
+// so long types don't bury the values, as in a type mismatch
+// between two instances of a deeply nested generic type.
+// A longer anonymous struct or interface type is written as
+// struct{…} or interface{…}, a longer generic type leaves out
+// its type arguments, as in Cache[…], and other long types,
+// such as maps and slices, are abbreviated inside.
+// MaxTypeLen(0) means no limit. The default is 40.
+// MaxTypeLen panics if n is negative.
+func MaxTypeLen(n int) Option {
+	if n < 0 {
+		panic("diff: negative MaxTypeLen")
+	}
+	return Option{func(c *config) {
+		c.display.maxTypeLen = n
+	}}
+}
+
+// Width limits the length of each line of output from
+// EmitAuto to about n characters, so that long values don't
+// make lines that wrap in a terminal. Both sides of a
+// difference are shortened by leaving out their middle,
+// for example:
+//
+//	T.Body: "lorem ips…laborum." != "hello, w…goodbye."
+//
+// Other lines are shortened at their end.
+// Width(-1) uses the width given by the COLUMNS
+// environment variable, as set by many shells, or 80.
+// Width(0), the default, means no limit.
+func Width(n int) Option {
+	return Option{func(c *config) {
+		if n < 0 {
+			n = envWidth()
+		}
+		c.width = n
+	}}
+}
+
+// PointerIDs controls whether the pointers in the values
+// written by EmitFull and FullValues are numbered, so that
+// sharing shows in the output. Each pointer is written
+// with the type it points to and a number, the same for
+// each appearance of the same pointer in the output of
+// one comparison, in either value, for example:
+//
+//	a:
+//	    &pkg.List#1{
+//	        Head: &pkg.Node#2{N:1},
+//	        Tail: &pkg.Node#2,
+//	    }
+//	b:
+//	    &pkg.List#3{
+//	        Head: &pkg.Node#4{N:1},
+//	        Tail: &pkg.Node#5{N:1},
+//	    }
+//
+// The value a pointer points to is written the first time
+// it appears in each value, and left out after that.
+// The default is false.
+func PointerIDs(b bool) Option {
+	return Option{func(c *config) {
+		c.pointerIDs = b
+	}}
+}
+
+// FullWidth limits the length of each line of the values
+// written by EmitFull and FullValues to n characters.
+// A longer line is cut off, with a note of how many
+// characters were left out, for example:
+//
+//	Body: "lorem ipsum dolor…[1048520 more]
+//
+// This keeps a long string or byte slice from making
+// an unmanageable line, even when it's not what differs.
+// FullWidth(0), the default, means no limit.
+// FullWidth panics if n is negative.
+func FullWidth(n int) Option {
+	if n < 0 {
+		panic("diff: negative FullWidth")
+	}
+	return Option{func(c *config) {
+		c.fullWidth = n
+	}}
+}
+
+// Color controls whether EmitAuto output is colored
+// with ANSI escape sequences: the value in a in red and
+// the value in b in green.
+// If Color is not given, output is colored automatically
+// by these rules, in order:
+// if the NO_COLOR environment variable is set and not empty,
+// output is not colored; if FORCE_COLOR is set and not empty,
+// it is colored; otherwise, it is colored only by Log,
+// when the logger writes to a terminal.
+func Color(b bool) Option {
+	return Option{func(c *config) {
+		c.color = colorNever
+		if b {
+			c.color = colorAlways
+		}
+	}}
+}
+
+// Redact hides the values of struct fields with the given
+// names in output, writing [REDACTED] in their place,
+// for example:
+//
+//	T.Password: [REDACTED] != [REDACTED]
+//
+// The fields are still compared as usual.
+// This keeps secrets out of test logs.
+// A field can also be redacted by its struct tag;
+// see the package documentation.
+// Redact may be given more than once to add more names.
+func Redact(names ...string) Option {
+	return Option{func(c *config) {
+		m := map[string]bool{}
+		for name := range c.display.redact {
+			m[name] = true
+		}
+		for _, name := range names {
+			m[name] = true
+		}
+		c.display.redact = m
+	}}
+}
+
+// Display sets a function to change how values are shown
+// in output. It is called with each value to be written,
+// and the path where it was found; if it returns ok,
+// display is written in place of the value.
+// This can hide, shorten, or summarize values,
+// for example:
+//
+//	diff.Display(func(p diff.Path, v reflect.Value) (string, bool) {
+//		if strings.HasSuffix(p.String(), ".Body") {
+//			return fmt.Sprintf("<%d bytes>", v.Len()), true
+//		}
+//		return "", false
+//	})
+//
+// If display is used for a pair of unequal values,
+// they are reported as one difference, using display,
+// rather than being compared further.
+// Display doesn't affect whether values are equal.
+// Map keys are always shown as they are.
+func Display(f func(path Path, v reflect.Value) (display string, ok bool)) Option {
+	if f == nil {
+		panic("diff: nil Display func")
+	}
+	return Option{func(c *config) {
+		c.display.hook = f
+	}}
+}
+
+// Prefix sets a label to write at the start of each line of output.
+// This helps tell apart the differences from several
+// comparisons made in one test or function.
+func Prefix(s string) Option {
+	return Option{func(c *config) {
+		c.prefix = s
+	}}
+}
+
+// Location sets the source location to report the differences at,
+// for output formats that attach one, such as EmitSARIF
+// and EmitGitHubActions.
+// File is a path, usually relative to the root of the
+// repository, and line is a line number in it,
+// or 0 to refer to the whole file.
+// Location panics if file is empty or line is negative.
+func Location(file string, line int) Option {
+	if file == "" {
+		panic("diff: empty Location file")
+	}
+	if line < 0 {
+		panic("diff: negative Location line")
+	}
+	return Option{func(c *config) {
+		c.location = location{file, line}
+	}}
+}
+
 // Outputter accepts log output.
 // It is satisfied by *log.Logger.
 type Outputter interface {
@@ -199,6 +1410,9 @@ type Outputter interface {
 // Logger sets the output for Log to the given object.
 // It has no effect on Each or Test.
 func Logger(out Outputter) Option {
+	if out == nil {
+		panic("diff: nil Logger")
+	}
 	return Option{func(c *config) {
 		c.output = out
 	}}
@@ -1,8 +1,10 @@
 package diff_test
 
 import (
+	"bytes"
 	"fmt"
 	"math"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -59,6 +61,54 @@ func TestTimeFormat(t *testing.T) {
 	}
 }
 
+func TestDurations(t *testing.T) {
+	type T struct{ D time.Duration }
+	a, b := T{1500 * time.Millisecond}, T{1502 * time.Millisecond}
+	cases := []struct {
+		name string
+		opt  []diff.Option
+		want string
+	}{
+		{"default", nil, "diff_test.T.D: 1.5s != 1.502s\n"},
+		{"delta", []diff.Option{diff.DurationDelta}, "diff_test.T.D: 1.5s != 1.502s (Δ2ms)\n"},
+		{"within", []diff.Option{diff.DurationTolerance(2 * time.Millisecond)}, ""},
+		{"beyond", []diff.Option{diff.DurationTolerance(time.Millisecond), diff.DurationDelta}, "diff_test.T.D: 1.5s != 1.502s (Δ2ms)\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, a, b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("diff = %q, want %q", got, tt.want)
+			}
+			got = ""
+			diff.Each((*stringPrinter)(&got).Printf, b, a, tt.opt...)
+			if (got == "") != (tt.want == "") {
+				t.Errorf("reversed diff = %q", got)
+			}
+		})
+	}
+}
+
+func TestUintptrHandles(t *testing.T) {
+	type T struct{ H uintptr }
+	cases := []struct {
+		a, b T
+		want string
+	}{
+		{T{0x1000}, T{0x2000}, ""},
+		{T{0}, T{0}, ""},
+		{T{0}, T{0x2000}, "diff_test.T.H: 0 != 8192\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		diff.Each((*stringPrinter)(&got).Printf, tt.a, tt.b, diff.UintptrHandles(true))
+		if got != tt.want {
+			t.Errorf("Each(%v, %v) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
 func TestZeroFields(t *testing.T) {
 	type C struct{ A, B int }
 	t0 := C{0, 2}
@@ -84,3 +134,936 @@ func TestZeroFields(t *testing.T) {
 		}
 	})
 }
+
+func TestCollapseEqual(t *testing.T) {
+	a := make([]int, 10)
+	b := make([]int, 10)
+	b[2] = 1
+	b[3] = 1
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.CollapseEqual(2))
+	want := "[]int[0..1]: (equal, 2 elements)\n" +
+		"[]int[2]: 0 != 1\n" +
+		"[]int[3]: 0 != 1\n" +
+		"[]int[4..9]: (equal, 6 elements)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, a, diff.CollapseEqual(2))
+	if got != "" {
+		t.Errorf("equal slices: got %q, want no output", got)
+	}
+
+	var aa, ba [100]int
+	ba[50] = 1
+	got = ""
+	diff.Each(gotp.Printf, aa, ba, diff.CollapseEqual(2))
+	want = "[100]int[0..49]: (equal, 50 elements)\n" +
+		"[100]int[50]: 0 != 1\n" +
+		"[100]int[51..99]: (equal, 49 elements)\n"
+	if got != want {
+		t.Errorf("bad array diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.CollapseEqual(2), diff.PathJSONPointer)
+	want = "[0:2]: (equal, 2 elements)\n" +
+		"/2: 0 != 1\n" +
+		"/3: 0 != 1\n" +
+		"[4:10]: (equal, 6 elements)\n"
+	if got != want {
+		t.Errorf("bad pointer diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestElemContext(t *testing.T) {
+	a := []int{0, 1, 2, 3, 4, 5, 6, 7, 8}
+	b := []int{0, 1, 2, 3, 9, 5, 6, 7, 8}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.ElemContext(1), diff.CollapseEqual(1))
+	want := "[]int[0..2]: (equal, 3 elements)\n" +
+		"[]int[3]: (context) 3\n" +
+		"[]int[4]: 4 != 9\n" +
+		"[]int[5]: (context) 5\n" +
+		"[]int[6..8]: (equal, 3 elements)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	type T struct{ A, B string }
+	a := T{A: "x", B: "1\n2\n"}
+	b := T{A: "y", B: "1\n3\n"}
+	var got string
+	f := func(format string, arg ...any) (int, error) {
+		s := fmt.Sprintf(format, arg...)
+		got += s
+		return len(s), nil
+	}
+	diff.Each(f, a, b, diff.Prefix("response: "))
+	want := `response: diff_test.T.A: "x" != "y"` + "\n" +
+		"response: diff_test.T.B: --- a\n" +
+		"response: +++ b\n" +
+		"response: @@ -1,2 +1,2 @@\n" +
+		"response:  1\n" +
+		"response: -2\n" +
+		"response: +3\n" +
+		"response: \n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestEmitEqual(t *testing.T) {
+	type Inner struct{ X, Y int }
+	type T struct {
+		Name   string
+		Age    int
+		Inner  *Inner
+		Tags   []string
+		Secret string `diff:"redact"`
+	}
+	a := T{"a", 1, &Inner{1, 2}, []string{"x"}, "s"}
+	b := T{"a", 2, &Inner{1, 3}, []string{"x"}, "s"}
+	cases := []struct {
+		name string
+		opt  diff.Option
+		want string
+	}{
+		{"auto", diff.EmitAuto, "" +
+			"diff_test.T.Name: = \"a\"\n" +
+			"diff_test.T.Age: 1 != 2\n" +
+			"diff_test.T.Inner.X: = 1\n" +
+			"diff_test.T.Inner.Y: 2 != 3\n" +
+			"diff_test.T.Tags[0]: = \"x\"\n" +
+			"diff_test.T.Secret: = [REDACTED]\n"},
+		{"tree", diff.EmitTree, "" +
+			"diff_test.T\n" +
+			tab + ".Name: = \"a\"\n" +
+			tab + ".Age: 1 != 2\n" +
+			tab + ".Inner\n" +
+			tab + tab + ".X: = 1\n" +
+			tab + tab + ".Y: 2 != 3\n" +
+			tab + ".Tags[0]: = \"x\"\n" +
+			tab + ".Secret: = [REDACTED]\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, a, b, tt.opt, diff.EmitEqual(true))
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitPathOnly, diff.EmitEqual(true))
+	if want := "diff_test.T.Age\ndiff_test.T.Inner.Y\n"; got != want {
+		t.Errorf("EmitPathOnly: got %q, want %q", got, want)
+	}
+}
+
+func TestEmitEqualRecorded(t *testing.T) {
+	a := []string{"n", "x", "u"}
+	b := []string{"n", "y", "u"}
+	var want string
+	diff.Each((*stringPrinter)(&want).Printf, a, b, diff.EmitEqual(true))
+	for _, opt := range []struct {
+		name string
+		opt  diff.Option
+	}{
+		{"AggregateRepeats", diff.AggregateRepeats(5)},
+		{"ContainerSummary", diff.ContainerSummary(true)},
+		{"Similarity", diff.Similarity(true)},
+	} {
+		t.Run(opt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, a, b, diff.EmitEqual(true), opt.opt)
+			if !strings.HasSuffix(got, want) {
+				t.Errorf("got:\n%s\nwant it to end with:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// TestRecordedFailFast checks that the options that record
+// the differences in a container before writing them
+// report what FailFast and EmitEqual would report without them.
+func TestRecordedFailFast(t *testing.T) {
+	type T struct {
+		Tags []string
+		Env  map[string]string
+	}
+	a := T{[]string{"n", "x", "y", "u"}, map[string]string{"k": "v", "m": "1"}}
+	b := T{[]string{"n", "p", "q", "u"}, map[string]string{"k": "v", "m": "2"}}
+	for _, base := range []struct {
+		name string
+		opt  []diff.Option
+	}{
+		{"FailFast", []diff.Option{diff.FailFast(true)}},
+		{"EmitEqual", []diff.Option{diff.EmitEqual(true)}},
+		{"both", []diff.Option{diff.FailFast(true), diff.EmitEqual(true)}},
+	} {
+		var want string
+		diff.Each((*stringPrinter)(&want).Printf, a, b, base.opt...)
+		for _, opt := range []struct {
+			name string
+			opt  diff.Option
+		}{
+			{"AggregateRepeats", diff.AggregateRepeats(2)},
+			{"ContainerSummary", diff.ContainerSummary(true)},
+			{"Similarity", diff.Similarity(true)},
+		} {
+			t.Run(base.name+"/"+opt.name, func(t *testing.T) {
+				var got string
+				diff.Each((*stringPrinter)(&got).Printf, a, b, append(base.opt, opt.opt)...)
+				if base.name == "EmitEqual" {
+					// Summaries add lines; the rest must be the same.
+					for _, line := range strings.SplitAfter(want, "\n") {
+						if !strings.Contains(got, line) {
+							t.Errorf("got:\n%s\nwant a line %q", got, line)
+						}
+					}
+					return
+				}
+				if got != want {
+					t.Errorf("got:\n%s\nwant:\n%s", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestMaxDiffs(t *testing.T) {
+	a := []int{0, 0, 0, 0}
+	b := []int{1, 1, 1, 1}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.MaxDiffs(1))
+	want := "[]int[0]: 0 != 1\n" +
+		"output truncated, 3 more differences\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestMaxContainerDiffs(t *testing.T) {
+	type Item struct{ A, B, C int }
+	type T struct {
+		Items []Item
+		Tags  map[string]int
+		N     int
+	}
+	a := T{
+		Items: []Item{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}, {0, 0, 0}},
+		Tags:  map[string]int{"x": 0, "y": 0},
+		N:     0,
+	}
+	b := T{
+		Items: []Item{{1, 1, 1}, {1, 1, 1}, {1, 1, 1}, {1, 1, 1}},
+		Tags:  map[string]int{"x": 1, "y": 1},
+		N:     1,
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.MaxContainerDiffs(2))
+	want := "diff_test.T.Items[0].A: 0 != 1\n" +
+		"diff_test.T.Items[0].B: 0 != 1\n" +
+		"diff_test.T.Items[0]: …and 1 more in this struct\n" +
+		"diff_test.T.Items[1].A: 0 != 1\n" +
+		"diff_test.T.Items[1].B: 0 != 1\n" +
+		"diff_test.T.Items[1]: …and 1 more in this struct\n" +
+		"diff_test.T.Items: …and 2 more in this slice\n" +
+		"diff_test.T.Tags[\"x\"]: 0 != 1\n" +
+		"diff_test.T.Tags[\"y\"]: 0 != 1\n" +
+		"…and 1 more in this struct\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	a := []int{0, 0, 0, 0}
+	b := []int{1, 1, 1, 1}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.MaxBytes(40))
+	want := "[]int[0]: 0 != 1\n" +
+		"[]int[1]: 0 != 1\n" +
+		"output truncated, 2 more differences\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestAggregateRepeats(t *testing.T) {
+	type Item struct{ Name, Version int }
+	var a, b []Item
+	for i := 0; i < 5; i++ {
+		a = append(a, Item{i, 1})
+		b = append(b, Item{i, 2})
+	}
+	b[3].Name = 9
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.AggregateRepeats(3))
+	want := "[]diff_test.Item[*].Version: 5 elements differ: 1 != 2\n" +
+		"[]diff_test.Item[3].Name: 3 != 9\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.AggregateRepeats(3), diff.PathJQ)
+	want = ".[*].Version: 5 elements differ: 1 != 2\n" +
+		".[3].Name: 3 != 9\n"
+	if got != want {
+		t.Errorf("bad jq diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.AggregateRepeats(3), diff.FailFast(true))
+	want = "[]diff_test.Item[0].Version: 1 != 2\n"
+	if got != want {
+		t.Errorf("bad FailFast diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	// EmitFull has no room for a summary,
+	// so it reports each element in full.
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.AggregateRepeats(3), diff.EmitFull)
+	want = ""
+	diff.Each((*stringPrinter)(&want).Printf, a, b, diff.EmitFull)
+	if got != want {
+		t.Errorf("bad full diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestMapSets(t *testing.T) {
+	a := map[string]bool{"x": true, "y": true, "z": false}
+	b := map[string]bool{"y": true, "w": true}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.MapSets(true))
+	want := "map[string]bool[\"w\"]: (added)\n" +
+		"map[string]bool[\"x\"]: (removed)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	diff.Test(t, t.Errorf,
+		map[int]struct{}(nil),
+		map[int]struct{}{},
+		diff.MapSets(true))
+}
+
+func TestSliceSet(t *testing.T) {
+	type Tags []string
+	type T struct {
+		Tags  Tags
+		Order []string
+	}
+	a := T{Tags: Tags{"x", "y", "y", "z"}, Order: []string{"p", "q"}}
+	b := T{Tags: Tags{"z", "w", "x"}, Order: []string{"q", "p"}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.SliceSet[Tags]())
+	want := "diff_test.T.Tags[1]: (removed) \"y\"\n" +
+		"diff_test.T.Tags[1]: (added) \"w\"\n" +
+		"diff_test.T.Order[0]: \"p\" != \"q\"\n" +
+		"diff_test.T.Order[1]: \"q\" != \"p\"\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestNormalizeStrings(t *testing.T) {
+	type T struct {
+		Name string
+		Data []byte
+	}
+	a := T{Name: "Caf\u00e9", Data: []byte("e\u0301")}
+	b := T{Name: "Cafe\u0301", Data: []byte("\u00e9")}
+	// A toy normalizer for the one character used here.
+	nfc := strings.NewReplacer("e\u0301", "\u00e9").Replace
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.NormalizeStrings(nfc))
+	if got != "" {
+		t.Errorf("normalized diff = %q, want empty", got)
+	}
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.NormalizeStrings(nfc), diff.NormalizeStrings(strings.ToLower))
+	if got != "" {
+		t.Errorf("composed diff = %q, want empty", got)
+	}
+	got = ""
+	diff.Each(gotp.Printf, a, b)
+	want := "diff_test.T.Name: \"Caf\\u00e9\" != \"Cafe\\u0301\"\n" +
+		"diff_test.T.Data: \"e\\u0301\" != \"\\u00e9\"\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	a := "one\r\ntwo\r\nthree\r\n"
+	b := "one\ntwo\nthree\n"
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.NormalizeLineEndings(true))
+	if got != "" {
+		t.Errorf("normalized diff = %q, want empty", got)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, "one\nTWO\nthree\n", diff.NormalizeLineEndings(true))
+	want := "--- a\n+++ b\n@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Strings(gotp.Printf, a, b, diff.NormalizeLineEndings(true))
+	if got != "" {
+		t.Errorf("Strings normalized diff = %q, want empty", got)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b)
+	if got == "" {
+		t.Errorf("unnormalized diff is empty, want difference")
+	}
+}
+
+type typedNilErr struct{}
+
+func (*typedNilErr) Error() string { return "typedNilErr" }
+
+func TestEqualTypedNil(t *testing.T) {
+	type T struct {
+		Err error
+		V   any
+	}
+	a := T{Err: (*typedNilErr)(nil), V: []int(nil)}
+	b := T{}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EqualTypedNil(true))
+	diff.Each(gotp.Printf, nil, (*int)(nil), diff.EqualTypedNil(true))
+	if got != "" {
+		t.Errorf("EqualTypedNil(true) diff = %q, want empty", got)
+	}
+
+	got = ""
+	a.V = []int{}
+	diff.Each(gotp.Printf, a, b, diff.EqualTypedNil(true))
+	want := "diff_test.T.V: []int{} != nil\n"
+	if got != want {
+		t.Errorf("non-nil diff = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b)
+	if !strings.Contains(got, "T.Err:") {
+		t.Errorf("default diff = %q, want a difference in Err", got)
+	}
+}
+
+func TestNaNMapKeys(t *testing.T) {
+	a := map[float64]string{1: "x", NaN: "n"}
+	b := map[float64]string{1: "x", NaN: "m"}
+	var got string
+	gotp := (*stringPrinter)(&got)
+
+	diff.Each(gotp.Printf, a, b)
+	want := "map[float64]string[NaN]: (removed)\n" +
+		"map[float64]string[NaN]: (added) \"m\"\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.EqualNaN)
+	want = "map[float64]string[NaN]: \"n\" != \"m\"\n"
+	if got != want {
+		t.Errorf("bad diff with EqualNaN")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	a[NaN] = "m"
+	b[NaN] = "n"
+	diff.Each(gotp.Printf, a, b, diff.EqualNaN)
+	if got != "" {
+		t.Errorf("EqualNaN diff = %q, want empty", got)
+	}
+}
+
+func TestFullValues(t *testing.T) {
+	type User struct {
+		Name string
+		Age  int
+	}
+	type T struct {
+		Users map[string]User
+		N     int
+	}
+	a := T{Users: map[string]User{}, N: 1}
+	b := T{Users: map[string]User{"bob": {"Bob", 42}}, N: 2}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.FullValues(true))
+	want := "diff_test.T.Users[\"bob\"]: (added) {Name:\"Bob\", ...}\n" +
+		"b:\n" +
+		tab + "diff_test.User{\n" +
+		tab + tab + "Name: \"Bob\",\n" +
+		tab + tab + "Age:  42,\n" +
+		tab + "}\n" +
+		"diff_test.T.N: 1 != 2\n" +
+		"a:\n" + tab + "int(1)\n" +
+		"b:\n" + tab + "int(2)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestOptionValidation(t *testing.T) {
+	type T struct {
+		A int
+		b int
+	}
+	cases := []struct {
+		name string
+		f    func()
+		want string
+	}{
+		{"Transform", func() { diff.Transform[int](nil) }, "diff: nil Transform func"},
+		{"Format", func() { diff.Format[int](nil) }, "diff: nil Format func"},
+		{"Logger", func() { diff.Logger(nil) }, "diff: nil Logger"},
+		{"ZeroFields missing", func() { diff.ZeroFields[T]("C") }, "diff: field not found: C"},
+		{"ZeroFields unexported", func() { diff.ZeroFields[T]("b") }, "diff: ZeroFields field b is unexported"},
+		{"ZeroFields non-struct", func() { diff.ZeroFields[int]("A") }, "diff: ZeroFields type int is not a struct"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if got := recover(); got != tt.want {
+					t.Errorf("panic = %v, want %q", got, tt.want)
+				}
+			}()
+			tt.f()
+		})
+	}
+}
+
+func TestZeroOption(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, 1, 2, diff.Option{}, diff.OptionList(diff.Option{}))
+	if want := "int(1) != int(2)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	type T struct {
+		A int
+		C time.Time
+		D int `diff:"ignorezero"`
+		E any
+	}
+	a := T{1, time.Time{}, 0, 1}
+	b := T{2, time.Time{}, 3, "a"}
+	var buf bytes.Buffer
+	diff.Each((*stringPrinter)(new(string)).Printf, a, b, diff.Trace(&buf))
+	want := "(root): diff_test.T\n" +
+		"diff_test.T.A: int\n" +
+		"diff_test.T.C: time.Time transformed, equal\n" +
+		"diff_test.T.D: skipped, zero (tag ignorezero)\n" +
+		"diff_test.T.E: interface {}\n" +
+		"diff_test.T.E: different types int and string\n"
+	if got := buf.String(); got != want {
+		t.Errorf("bad trace")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	a := make([]int, 1000)
+	b := make([]int, 1000)
+	for i := range b {
+		b[i] = 1
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.FailFast(true))
+	if want := "[]int[0]: 0 != 1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	n := 0
+	visited := 0
+	tr := diff.Transform(func(x int) any { visited++; return x })
+	d := diff.New(diff.FailFast(true), tr)
+	d.Each(func(string, ...any) (int, error) { n++; return 0, nil }, a, b)
+	if n != 1 {
+		t.Errorf("FailFast called f %d times, want 1", n)
+	}
+	if visited > 2 {
+		t.Errorf("FailFast transformed %d values, want at most 2", visited)
+	}
+	if c := d.Compare(a, b); len(c) != 1 {
+		t.Errorf("FailFast Compare found %d changes, want 1", len(c))
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	a := make([]int, 100000)
+	b := make([]int, 100000)
+	for i := range b {
+		b[i] = i % 2
+	}
+	slow := diff.Transform(func(x int) any {
+		for t0 := time.Now(); time.Since(t0) < 10*time.Microsecond; {
+		}
+		return x
+	})
+	var got string
+	gotp := (*stringPrinter)(&got)
+	start := time.Now()
+	diff.Each(gotp.Printf, a, b, slow, diff.Timeout(10*time.Millisecond))
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("comparison took %v, want about 10ms", d)
+	}
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	last := lines[len(lines)-1]
+	want := fmt.Sprintf("comparison timed out after 10ms and %d differences", len(lines)-1)
+	if last != want {
+		t.Errorf("last line = %q, want %q", last, want)
+	}
+
+	found := diff.New(slow, diff.Timeout(10*time.Millisecond)).Compare(a, b)
+	if len(found) == 0 {
+		t.Fatalf("Compare found no changes")
+	}
+	ch := found[len(found)-1]
+	if want := "comparison timed out after 10ms"; ch.String() != want {
+		t.Errorf("Compare: last change = %q, want %q", ch, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, 1, 2, diff.Timeout(time.Hour))
+	if want := "int(1) != int(2)\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestChanMetadata(t *testing.T) {
+	type T struct{ C chan int }
+	full := make(chan int, 2)
+	full <- 1
+	cases := []struct {
+		name string
+		a, b T
+		want string
+	}{
+		{"same shape", T{make(chan int)}, T{make(chan int)}, ""},
+		{"nil", T{nil}, T{nil}, ""},
+		{"cap", T{make(chan int)}, T{make(chan int, 2)}, "diff_test.T.C: cap 0 != 2\n"},
+		{"len", T{full}, T{make(chan int, 2)}, "diff_test.T.C: len 1 != 0\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, diff.ChanMetadata(true))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, T{make(chan int)}, T{make(chan int)})
+	if got == "" {
+		t.Errorf("default: distinct channels compared equal")
+	}
+}
+
+func TestSortFields(t *testing.T) {
+	type Inner struct{ Z, A int }
+	type T struct {
+		Name  string
+		Inner Inner
+		Age   int
+	}
+	a := T{"a", Inner{1, 1}, 1}
+	b := T{"b", Inner{2, 2}, 2}
+	cases := []struct {
+		name string
+		opt  diff.Option
+		want string
+	}{
+		{"declared", diff.SortFields(false), "" +
+			"diff_test.T.Name: \"a\" != \"b\"\n" +
+			"diff_test.T.Inner.Z: 1 != 2\n" +
+			"diff_test.T.Inner.A: 1 != 2\n" +
+			"diff_test.T.Age: 1 != 2\n"},
+		{"sorted", diff.SortFields(true), "" +
+			"diff_test.T.Age: 1 != 2\n" +
+			"diff_test.T.Inner.A: 1 != 2\n" +
+			"diff_test.T.Inner.Z: 1 != 2\n" +
+			"diff_test.T.Name: \"a\" != \"b\"\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, a, b, tt.opt)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreZeroTime(t *testing.T) {
+	type T struct {
+		Created time.Time
+		Updated *time.Time
+	}
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	later := now.Add(time.Hour)
+	var zero time.Time
+	cases := []struct {
+		name string
+		a, b T
+		want string
+	}{
+		{"zero a", T{}, T{Created: now}, ""},
+		{"zero b", T{Created: now}, T{}, ""},
+		{"nil pointer", T{Updated: nil}, T{Updated: &now}, ""},
+		{"pointer to zero", T{Updated: &zero}, T{Updated: &now}, ""},
+		{"nil and zero", T{Updated: nil}, T{Updated: &zero}, ""},
+		{"set", T{Created: now}, T{Created: later},
+			"diff_test.T.Created: 2022-01-02T03:04:05Z != 2022-01-02T04:04:05Z (1h0m0s)\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, diff.IgnoreZeroTime(true))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareKind(t *testing.T) {
+	type Celsius float64
+	type T struct {
+		X float64
+		C Celsius
+		N int
+	}
+	approx := diff.CompareKind(reflect.Float64, func(a, b reflect.Value) bool {
+		return math.Abs(a.Float()-b.Float()) < 0.01
+	})
+	cases := []struct {
+		name string
+		a, b T
+		want string
+	}{
+		{"close", T{1, 20, 1}, T{1.001, 20.005, 1}, ""},
+		{"far", T{1, 20, 1}, T{1.1, 21, 1}, "diff_test.T.X: 1 != 1.1\ndiff_test.T.C: 20 != 21\n"},
+		{"other kind", T{1, 20, 1}, T{1, 20, 2}, "diff_test.T.N: 1 != 2\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, approx)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("CompareKind(reflect.Struct, ...) didn't panic")
+		}
+	}()
+	diff.CompareKind(reflect.Struct, func(a, b reflect.Value) bool { return true })
+}
+
+type typePair[K, V any] struct {
+	K K
+	V V
+}
+
+func TestMaxTypeLen(t *testing.T) {
+	type P = typePair[map[string][]int, typePair[int, string]]
+	type S = map[string]struct{ LongFieldName, OtherLongFieldName int }
+	cases := []struct {
+		opt  diff.Option
+		a, b any
+		want string
+	}{
+		{diff.OptionList(), P{}, 1, "diff_test.typePair[…]{K:nil, ...} != int(1)\n"},
+		{diff.MaxTypeLen(0), P{}, 1, "diff_test.typePair[map[string][]int,kr.dev/diff_test.typePair[int,string]]{K:nil, ...} != int(1)\n"},
+		{diff.MaxTypeLen(80), P{}, 1, "diff_test.typePair[map[string][]int,kr.dev/diff_test.typePair[int,string]]{K:nil, ...} != int(1)\n"},
+		{diff.OptionList(), S{}, 1, "map[string]struct{…}{} != int(1)\n"},
+		{diff.MaxTypeLen(0), S{}, 1, "map[string]struct{ LongFieldName int; OtherLongFieldName int }{} != int(1)\n"},
+		{diff.MaxTypeLen(10), typePair[int, int]{}, 1, "diff_test.typePair[…]{K:0, ...} != int(1)\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		diff.Each((*stringPrinter)(&got).Printf, tt.a, tt.b, tt.opt)
+		if got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestMaxTypeLenPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("MaxTypeLen(-1) did not panic")
+		}
+	}()
+	diff.MaxTypeLen(-1)
+}
+
+func TestContainerSummary(t *testing.T) {
+	type T struct {
+		Env  map[string]string
+		Tags []string
+		A    [3]int
+	}
+	a := T{Env: map[string]string{"a": "1", "b": "2", "c": "3"}, Tags: []string{"x", "y"}, A: [3]int{1, 2, 3}}
+	b := T{Env: map[string]string{"a": "1", "b": "x", "d": "4", "e": "5"}, Tags: []string{"x", "z"}, A: [3]int{0, 2, 0}}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, a, b, diff.ContainerSummary(true))
+	want := `diff_test.T.Env: len 3→4, 2 added, 1 removed, 1 changed
+diff_test.T.Env["b"]: "2" != "x"
+diff_test.T.Env["c"]: (removed)
+diff_test.T.Env["d"]: (added) "4"
+diff_test.T.Env["e"]: (added) "5"
+diff_test.T.Tags: len 2, 1 changed
+diff_test.T.Tags[1]: "y" != "z"
+diff_test.T.A: len 3, 2 changed
+diff_test.T.A[0]: 1 != 0
+diff_test.T.A[2]: 3 != 0
+`
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	// An element with several differences counts once.
+	got = ""
+	diff.Each((*stringPrinter)(&got).Printf, []T{a, a}, []T{b, a},
+		diff.ContainerSummary(true), diff.EmitPathOnly)
+	if strings.Contains(got, "len ") {
+		t.Errorf("EmitPathOnly wrote a summary:\n%s", got)
+	}
+	got = ""
+	diff.Each((*stringPrinter)(&got).Printf, []T{a, a}, []T{b, a}, diff.ContainerSummary(true))
+	if !strings.HasPrefix(got, "len 2, 1 changed\n") {
+		t.Errorf("got:\n%s\nwant first line %q", got, "len 2, 1 changed")
+	}
+
+	got = ""
+	diff.Each((*stringPrinter)(&got).Printf, a, a, diff.ContainerSummary(true))
+	if got != "" {
+		t.Errorf("equal values: got %q, want no output", got)
+	}
+
+	got = ""
+	diff.Each((*stringPrinter)(&got).Printf, a, b,
+		diff.ContainerSummary(true), diff.FailFast(true))
+	want = "diff_test.T.Env[\"b\"]: \"2\" != \"x\"\n"
+	if got != want {
+		t.Errorf("FailFast: got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	a := make([]int, 25)
+	b := make([]int, 25)
+	b[3], b[20] = 1, 1
+	m1 := map[string]int{"a": 1, "b": 2, "c": 3}
+	m2 := map[string]int{"a": 1, "b": 2, "d": 4}
+	cases := []struct {
+		name string
+		a, b any
+		opt  diff.Option
+		want string
+	}{
+		{"slice", a, b, diff.Similarity(true), "92% equal (2/25 differ)\n[]int[3]: 0 != 1\n[]int[20]: 0 != 1\n"},
+		{"map", m1, m2, diff.Similarity(true), "50% equal (2/4 differ)\nmap[string]int[\"c\"]: (removed)\nmap[string]int[\"d\"]: (added) 4\n"},
+		{"summary", a, b, diff.OptionList(diff.ContainerSummary(true), diff.Similarity(true)),
+			"len 25, 2 changed, 92% equal (2/25 differ)\n[]int[3]: 0 != 1\n[]int[20]: 0 != 1\n"},
+		{"rounded down", []int{0, 0, 0}, []int{0, 0, 1}, diff.Similarity(true), "66% equal (1/3 differ)\n[]int[2]: 0 != 1\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, tt.a, tt.b, tt.opt)
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+module kr.dev/diff/oteldiff
+
+go 1.18
+
+require (
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+	kr.dev/diff v0.0.0
+)
+
+require (
+	github.com/pkg/diff v0.0.0-20210226163009-20ebb0f2a09e // indirect
+	github.com/rogpeppe/go-internal v1.8.1 // indirect
+)
+
+replace kr.dev/diff => ../
@@ -0,0 +1,56 @@
+// Package oteldiff records differences between values
+// on OpenTelemetry trace spans, so that mismatches found
+// inside a service, such as by package shadow,
+// show up in its traces.
+package oteldiff
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"kr.dev/diff"
+)
+
+// Attribute keys set by this package.
+const (
+	KeyPath        = attribute.Key("diff.path")
+	KeyText        = attribute.Key("diff.text")
+	KeyDifferences = attribute.Key("diff.differences")
+	KeyPaths       = attribute.Key("diff.paths")
+)
+
+// EventName is the name of the span events added by Events.
+const EventName = "diff.difference"
+
+// Events compares a and b, adding an event to span for each
+// difference it finds, with attributes KeyPath and KeyText,
+// and sets attribute KeyDifferences on span to the number
+// of differences. It returns that number.
+// Output options, such as EmitFull, have no effect.
+func Events(span trace.Span, a, b any, opt ...diff.Option) int {
+	found := diff.New(opt...).Compare(a, b)
+	for _, c := range found {
+		span.AddEvent(EventName, trace.WithAttributes(
+			KeyPath.String(c.Path.String()),
+			KeyText.String(c.Text),
+		))
+	}
+	span.SetAttributes(KeyDifferences.Int(len(found)))
+	return len(found)
+}
+
+// Summarize is like Events, but instead of adding events,
+// it sets attribute KeyPaths on span to the paths of
+// the differences, so that a span with many differences
+// stays small. It also sets KeyDifferences.
+func Summarize(span trace.Span, a, b any, opt ...diff.Option) int {
+	found := diff.New(opt...).Compare(a, b)
+	paths := make([]string, len(found))
+	for i, c := range found {
+		paths[i] = c.Path.String()
+	}
+	span.SetAttributes(
+		KeyDifferences.Int(len(found)),
+		KeyPaths.StringSlice(paths),
+	)
+	return len(found)
+}
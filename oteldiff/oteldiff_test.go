@@ -0,0 +1,75 @@
+package oteldiff_test
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"kr.dev/diff"
+	"kr.dev/diff/oteldiff"
+)
+
+// span records the events and attributes set on it.
+// Its other methods panic.
+type span struct {
+	trace.Span
+	events []map[attribute.Key]string
+	attrs  map[attribute.Key]string
+}
+
+func (s *span) AddEvent(name string, opt ...trace.EventOption) {
+	if name != oteldiff.EventName {
+		panic("bad event name " + name)
+	}
+	c := trace.NewEventConfig(opt...)
+	s.events = append(s.events, attrMap(c.Attributes()))
+}
+
+func (s *span) SetAttributes(kv ...attribute.KeyValue) {
+	if s.attrs == nil {
+		s.attrs = map[attribute.Key]string{}
+	}
+	for k, v := range attrMap(kv) {
+		s.attrs[k] = v
+	}
+}
+
+// attrMap returns the attributes in kv,
+// with their values encoded as strings.
+func attrMap(kv []attribute.KeyValue) map[attribute.Key]string {
+	m := map[attribute.Key]string{}
+	for _, a := range kv {
+		m[a.Key] = a.Value.Emit()
+	}
+	return m
+}
+
+type T struct {
+	Name string
+	N    int
+}
+
+func TestEvents(t *testing.T) {
+	s := new(span)
+	n := oteldiff.Events(s, T{"a", 1}, T{"b", 2})
+	if n != 2 {
+		t.Errorf("Events = %d, want 2", n)
+	}
+	diff.Test(t, t.Errorf, s.events, []map[attribute.Key]string{
+		{oteldiff.KeyPath: "oteldiff_test.T.Name", oteldiff.KeyText: `"a" != "b"`},
+		{oteldiff.KeyPath: "oteldiff_test.T.N", oteldiff.KeyText: "1 != 2"},
+	})
+	diff.Test(t, t.Errorf, s.attrs[oteldiff.KeyDifferences], "2")
+}
+
+func TestSummarize(t *testing.T) {
+	s := new(span)
+	n := oteldiff.Summarize(s, T{"a", 1}, T{"a", 2})
+	if n != 1 || len(s.events) != 0 {
+		t.Errorf("Summarize = %d with %d events, want 1 with none", n, len(s.events))
+	}
+	diff.Test(t, t.Errorf, s.attrs, map[attribute.Key]string{
+		oteldiff.KeyDifferences: "1",
+		oteldiff.KeyPaths:       "[oteldiff_test.T.N]",
+	})
+}
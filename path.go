@@ -0,0 +1,212 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Path is the location of a value inside another value,
+// given as a sequence of steps (field selectors, indexes,
+// and map keys) from the root.
+type Path struct {
+	root  reflect.Type // type of the value at the root, if any
+	steps []step
+	style pathStyle
+}
+
+// String returns p in the syntax selected by the options
+// used to produce it. See PathGo, PathJQ, and PathJSONPointer.
+func (p Path) String() string {
+	s := formatPath(p.style, p.steps)
+	if p.style == pathGo && p.root != nil {
+		var b strings.Builder
+		writeTypeLimit(&b, p.root, maxAnonType)
+		s = b.String() + s
+	}
+	return s
+}
+
+// A pathStyle is a syntax for writing paths in the output.
+type pathStyle int
+
+const (
+	pathGo pathStyle = iota
+	pathJQ
+	pathPointer
+)
+
+// A stepKind identifies the kind of a path step.
+type stepKind int
+
+const (
+	stepIndex    stepKind = iota // [i], element of an array or slice
+	stepField                    // .Name, field of a struct
+	stepKey                      // [k], entry in a map
+	stepRange                    // [i..j], elements i through j inclusive
+	stepSlice                    // [i:j], bytes of a string
+	stepWildcard                 // [*], any element of an array or slice
+	stepPair                     // [i→j], element i of a paired with element j of b
+)
+
+// A step is one element of a path from the root value
+// to the location of a difference.
+type step struct {
+	kind  stepKind
+	i, j  int
+	field reflect.StructField
+	tag   *fieldTag // of field, if known
+	key   reflect.Value
+}
+
+func indexStep(i int) step         { return step{kind: stepIndex, i: i} }
+func keyStep(k reflect.Value) step { return step{kind: stepKey, key: k} }
+func rangeStep(i, j int) step      { return step{kind: stepRange, i: i, j: j} }
+func pairStep(i, j int) step       { return step{kind: stepPair, i: i, j: j} }
+func sliceStep(i, j int) step      { return step{kind: stepSlice, i: i, j: j} }
+
+// fieldStep returns a step to field f, whose parsed
+// diff tag is tag, or nil if it isn't known.
+func fieldStep(f reflect.StructField, tag *fieldTag) step {
+	return step{kind: stepField, field: f, tag: tag}
+}
+
+// formatPath writes path in the given style.
+// It does not include the type of the root value.
+func formatPath(style pathStyle, path []step) string {
+	var b strings.Builder
+	for _, s := range path {
+		s.writeTo(&b, style)
+	}
+	p := b.String()
+	if style == pathJQ && !strings.HasPrefix(p, ".") {
+		p = "." + p
+	}
+	return p
+}
+
+func (s step) writeTo(b *strings.Builder, style pathStyle) {
+	switch s.kind {
+	case stepIndex:
+		switch style {
+		case pathPointer:
+			fmt.Fprintf(b, "/%d", s.i)
+		default:
+			fmt.Fprintf(b, "[%d]", s.i)
+		}
+	case stepField:
+		switch style {
+		case pathJQ:
+			writeJQKey(b, s.jsonName())
+		case pathPointer:
+			writePointerToken(b, s.jsonName())
+		default:
+			b.WriteString("." + s.goName())
+		}
+	case stepKey:
+		switch style {
+		case pathJQ:
+			writeJQKey(b, keyString(s.key))
+		case pathPointer:
+			writePointerToken(b, keyString(s.key))
+		default:
+			fmt.Fprintf(b, "[%#v]", s.key)
+		}
+	case stepRange:
+		// JSON Pointer has no syntax for a range,
+		// so the pointer style uses jq's.
+		switch style {
+		case pathJQ, pathPointer:
+			fmt.Fprintf(b, "[%d:%d]", s.i, s.j+1)
+		default:
+			fmt.Fprintf(b, "[%d..%d]", s.i, s.j)
+		}
+	case stepSlice:
+		fmt.Fprintf(b, "[%d:%d]", s.i, s.j)
+	case stepPair:
+		switch style {
+		case pathPointer:
+			fmt.Fprintf(b, "/%d", s.i)
+		case pathJQ:
+			fmt.Fprintf(b, "[%d]", s.i)
+		default:
+			fmt.Fprintf(b, "[%d→%d]", s.i, s.j)
+		}
+	case stepWildcard:
+		b.WriteString("[*]")
+	default:
+		panic("diff: bad path step")
+	}
+}
+
+// writePointerToken writes name as a JSON Pointer
+// reference token, escaped as described in RFC 6901.
+func writePointerToken(b *strings.Builder, name string) {
+	name = strings.ReplaceAll(name, "~", "~0")
+	name = strings.ReplaceAll(name, "/", "~1")
+	b.WriteString("/" + name)
+}
+
+func writeJQKey(b *strings.Builder, name string) {
+	if isIdent(name) {
+		b.WriteString("." + name)
+	} else {
+		b.WriteString("[" + strconv.Quote(name) + "]")
+	}
+}
+
+// tagName returns the name given in the diff tag
+// of field step s, if any.
+func (s step) tagName() string {
+	if s.tag == nil {
+		return ""
+	}
+	return s.tag.name
+}
+
+// goName returns the name of field step s for a path
+// in Go notation: the name given in its diff tag,
+// if any, or its Go name.
+func (s step) goName() string {
+	if name := s.tagName(); name != "" {
+		return name
+	}
+	return s.field.Name
+}
+
+// jsonName returns the name of field step s for a path
+// in JSON-like notation: the name given in its diff tag,
+// if any, or the name encoding/json would use.
+func (s step) jsonName() string {
+	if name := s.tagName(); name != "" {
+		return name
+	}
+	name, _, _ := strings.Cut(s.field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return s.field.Name
+	}
+	return name
+}
+
+// keyString returns the string encoding/json would use
+// for map key k, or its default format if
+// encoding/json doesn't support k's type.
+func keyString(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	return fmt.Sprint(k)
+}
+
+func isIdent(s string) bool {
+	for i, c := range s {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
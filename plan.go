@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// A fieldPlan holds what walk needs to know about
+// one field of a struct type.
+type fieldPlan struct {
+	index int
+	step  step
+	tag   fieldTag
+}
+
+// structPlans maps each struct type seen so far
+// to its []fieldPlan.
+var structPlans sync.Map
+
+// sortedPlans is like structPlans,
+// for sortedStructPlan.
+var sortedPlans sync.Map
+
+// fieldTags maps each struct type seen so far
+// to the []fieldTag of its fields.
+var fieldTags sync.Map
+
+// structTags returns the parsed diff tags of the fields
+// of struct type t, indexed like the fields.
+// It parses the tags once per type.
+func structTags(t reflect.Type) []fieldTag {
+	if p, ok := fieldTags.Load(t); ok {
+		return p.([]fieldTag)
+	}
+	tags := make([]fieldTag, t.NumField())
+	for i := range tags {
+		tags[i] = parseTag(t.Field(i))
+	}
+	p, _ := fieldTags.LoadOrStore(t, tags)
+	return p.([]fieldTag)
+}
+
+// structPlan returns the plan for comparing values of
+// struct type t: one entry for each field to compare,
+// in order. Fields excluded by their tags are left out.
+// It computes the plan once per type.
+func structPlan(t reflect.Type) []fieldPlan {
+	if p, ok := structPlans.Load(t); ok {
+		return p.([]fieldPlan)
+	}
+	var plan []fieldPlan
+	tags := structTags(t)
+	for i := range tags {
+		if tags[i].skip {
+			continue
+		}
+		plan = append(plan, fieldPlan{index: i, step: fieldStep(t.Field(i), &tags[i]), tag: tags[i]})
+	}
+	p, _ := structPlans.LoadOrStore(t, plan)
+	return p.([]fieldPlan)
+}
+
+// sortedStructPlan is like structPlan,
+// but with the fields sorted by name, for SortFields.
+func sortedStructPlan(t reflect.Type) []fieldPlan {
+	if p, ok := sortedPlans.Load(t); ok {
+		return p.([]fieldPlan)
+	}
+	plan := append([]fieldPlan(nil), structPlan(t)...)
+	sort.SliceStable(plan, func(i, j int) bool {
+		return plan[i].step.field.Name < plan[j].step.field.Name
+	})
+	p, _ := sortedPlans.LoadOrStore(t, plan)
+	return p.([]fieldPlan)
+}
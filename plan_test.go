@@ -0,0 +1,31 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+type benchRecord struct {
+	ID    int
+	Name  string
+	Tags  []string
+	Score float64 `diff:"approx=1e-9"`
+	Note  string  `diff:"-"`
+}
+
+func BenchmarkStructs(b *testing.B) {
+	x := make([]benchRecord, 1000)
+	y := make([]benchRecord, 1000)
+	for i := range x {
+		x[i] = benchRecord{ID: i, Name: "name", Tags: []string{"a", "b"}, Score: 1}
+		y[i] = x[i]
+	}
+	y[500].Name = "other"
+	f := func(string, ...any) (int, error) { return 0, nil }
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		diff.Each(f, x, y)
+	}
+}
@@ -0,0 +1,44 @@
+package diff
+
+import (
+	"io"
+	"strings"
+)
+
+// Sprint formats v in the full form used by EmitFull:
+// Go syntax, with each element of a struct, map, or
+// array or slice of more than one element on its own line,
+// indented by four spaces, and with map keys and struct
+// fields aligned, for example:
+//
+//	&pkg.T{
+//	    Name: "a",
+//	    Tags: {
+//	        "x":   1,
+//	        "yyy": 2,
+//	    },
+//	}
+//
+// A pointer reached more than once is written in full
+// the first time only, and as ... after that.
+// Unlike the fmt package, Sprint never calls methods
+// such as String or Error, so it shows values as they are.
+func Sprint(v any) string {
+	var b strings.Builder
+	f := &formatter{
+		wantType:   true,
+		full:       true,
+		allowDepth: 1e8,
+		seen:       map[visit]bool{},
+		tab:        "    ",
+	}
+	f.writeTo(&b, rootValue(v), true, 1)
+	return b.String()
+}
+
+// Fprint writes v to w, formatted as by Sprint.
+// It returns the number of bytes written
+// and any write error encountered.
+func Fprint(w io.Writer, v any) (n int, err error) {
+	return io.WriteString(w, Sprint(v))
+}
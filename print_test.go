@@ -0,0 +1,40 @@
+package diff_test
+
+import (
+	"bytes"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestSprint(t *testing.T) {
+	type T struct {
+		N int
+		P *T
+	}
+	cyc := &T{N: 1}
+	cyc.P = cyc
+	cases := []struct {
+		v    any
+		want string
+	}{
+		{nil, "nil"},
+		{3, "int(3)"},
+		{[]int{1}, "[]int{1}"},
+		{[]int{1, 2}, "[]int{\n    1,\n    2,\n}"},
+		{cyc, "&diff_test.T{\n    N: 1,\n    P: ...,\n}"},
+	}
+	for _, tt := range cases {
+		if got := diff.Sprint(tt.v); got != tt.want {
+			t.Errorf("Sprint(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestFprint(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := diff.Fprint(&buf, []int{1})
+	if err != nil || n != buf.Len() || buf.String() != "[]int{1}" {
+		t.Errorf("Fprint = %d, %v; wrote %q", n, err, buf.String())
+	}
+}
@@ -0,0 +1,43 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// pointerEntries returns the entries of map m, whose keys
+// are pointers, in order of the values the keys point to.
+func pointerEntries(m reflect.Value) (a []mapEntry) {
+	iter := m.MapRange()
+	for iter.Next() {
+		a = append(a, mapEntry{iter.Key(), iter.Value()})
+	}
+	keys := make([]string, len(a))
+	for i, ent := range a {
+		keys[i] = fmt.Sprint(formatShort(nil, ent.k, false))
+	}
+	sort.Sort(byString{a, keys})
+	return a
+}
+
+// byString sorts entries by the strings in keys.
+type byString struct {
+	a    []mapEntry
+	keys []string
+}
+
+func (b byString) Len() int           { return len(b.a) }
+func (b byString) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byString) Swap(i, j int) {
+	b.a[i], b.a[j] = b.a[j], b.a[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// walkPointerKeys compares maps av and bv, of type t, whose
+// keys are pointers, pairing their entries by the values the
+// keys point to rather than by address, for PointerKeys.
+func (d *differ) walkPointerKeys(e emitfer, t reflect.Type, av, bv reflect.Value) {
+	d.config.helper()
+	d.walkEntries(e, t, pointerEntries(av), pointerEntries(bv))
+}
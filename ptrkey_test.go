@@ -0,0 +1,46 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestPointerKeys(t *testing.T) {
+	type K struct{ ID string }
+	index := func(v map[string]int) map[*K]int {
+		m := map[*K]int{}
+		for id, n := range v {
+			m[&K{id}] = n
+		}
+		return m
+	}
+	cases := []struct {
+		name string
+		a, b map[string]int
+		want string
+	}{
+		{"equal", map[string]int{"x": 1, "y": 2}, map[string]int{"x": 1, "y": 2}, ""},
+		{"value", map[string]int{"x": 1, "y": 2}, map[string]int{"x": 1, "y": 3},
+			"map[*diff_test.K]int[&diff_test.K{ID:\"y\"}]: 2 != 3\n"},
+		{"added", map[string]int{"x": 1}, map[string]int{"x": 1, "z": 3},
+			"map[*diff_test.K]int[&diff_test.K{ID:\"z\"}]: (added) 3\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, index(tt.a), index(tt.b), diff.PointerKeys(true))
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, index(map[string]int{"x": 1}), index(map[string]int{"x": 1}))
+	if got == "" {
+		t.Errorf("pointer keys matched by pointee without PointerKeys")
+	}
+}
@@ -0,0 +1,50 @@
+package diff
+
+import (
+	"net/url"
+	"reflect"
+)
+
+var (
+	reflectValues = reflect.TypeOf(url.Values(nil))
+	reflectURL    = reflect.TypeOf(url.URL{})
+)
+
+// isRawQuery reports whether field i of struct type t
+// is the RawQuery field of url.URL.
+func isRawQuery(t reflect.Type, i int) bool {
+	return t == reflectURL && t.Field(i).Name == "RawQuery"
+}
+
+// parseQueries decodes the query strings held in av and bv.
+// It reports false if either is not a valid query.
+func parseQueries(av, bv reflect.Value) (aq, bq reflect.Value, ok bool) {
+	a, err := url.ParseQuery(av.String())
+	if err != nil {
+		return aq, bq, false
+	}
+	b, err := url.ParseQuery(bv.String())
+	if err != nil {
+		return aq, bq, false
+	}
+	return reflect.ValueOf(a), reflect.ValueOf(b), true
+}
+
+// walkQuery compares av and bv, of type url.Values,
+// by key and by the values for each key,
+// without regard to their order.
+func (d *differ) walkQuery(e emitfer, av, bv reflect.Value) {
+	d.config.helper()
+	for _, k := range sortedKeys(av, bv) {
+		esub := e.sub(reflectValues, keyStep(k))
+		a, b := av.MapIndex(k), bv.MapIndex(k)
+		switch {
+		case !b.IsValid():
+			esub.emitf(a, b, "(removed)")
+		case !a.IsValid():
+			esub.emitf(a, b, "(added) %v", formatShort(&d.config.display, b, false))
+		default:
+			d.walkUnordered(esub, reflectStrings, a, b)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package diff_test
+
+import (
+	"net/url"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestQueryValues(t *testing.T) {
+	type T struct{ Q url.Values }
+	cases := []struct {
+		name string
+		a, b any
+		want string
+	}{
+		{"order", url.Values{"a": {"1", "2"}}, url.Values{"a": {"2", "1"}}, ""},
+		{"value", T{url.Values{"a": {"1", "2"}}}, T{url.Values{"a": {"1", "3"}}},
+			"diff_test.T.Q[\"a\"][1]: \"2\" != \"3\"\n"},
+		{"added", url.Values{}, url.Values{"a": {"1"}},
+			"url.Values[\"a\"]: (added) {\"1\"}\n"},
+		{"raw", url.URL{Path: "/x", RawQuery: "a=1&b=2&b=%33"}, url.URL{Path: "/x", RawQuery: "b=3&a=1&b=2"}, ""},
+		{"rawdiff", &url.URL{RawQuery: "a=1&b=2"}, &url.URL{RawQuery: "b=3&a=1"},
+			"url.URL.RawQuery[\"b\"][0]: \"2\" != \"3\"\n"},
+		{"invalid", url.URL{RawQuery: "a=%zz"}, url.URL{RawQuery: "a=1"},
+			"url.URL.RawQuery: \"a=%zz\" != \"a=1\"\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, diff.QueryValues(true))
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, url.URL{RawQuery: "a=1&b=2"}, url.URL{RawQuery: "b=2&a=1"})
+	if got == "" {
+		t.Errorf("query compared as values without QueryValues")
+	}
+}
@@ -0,0 +1,159 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A recorder is an emitter that saves its output
+// so it can be examined and replayed to another emitter.
+type recorder struct {
+	base   []step      // path to the root recorder
+	path   []typedStep // relative to the root recorder
+	parent *recorder
+	recs   *[]record // shared by all sub-recorders
+	stop   *bool     // set by emitf, for FailFast, if not nil
+	did    bool
+	noted  bool // for EmitEqual
+}
+
+type typedStep struct {
+	t reflect.Type
+	s step
+}
+
+type record struct {
+	path   []typedStep
+	note   bool
+	av, bv reflect.Value
+	format string
+	arg    []any
+}
+
+// newRecorder returns a root recorder for the location base.
+// If stop is not nil, recording a difference sets *stop,
+// ending the walk as FailFast does.
+func newRecorder(base []step, stop *bool) *recorder {
+	return &recorder{base: base, recs: new([]record), stop: stop}
+}
+
+func (r *recorder) emitf(av, bv reflect.Value, format string, arg ...any) {
+	for p := r; p != nil; p = p.parent {
+		p.did = true
+	}
+	if r.stop != nil {
+		*r.stop = true
+	}
+	*r.recs = append(*r.recs, record{r.path, false, av, bv, format, arg})
+}
+
+func (r *recorder) notef(format string, arg ...any) {
+	*r.recs = append(*r.recs, record{r.path, true, reflect.Value{}, reflect.Value{}, format, arg})
+}
+
+func (r *recorder) sub(t reflect.Type, s step) emitfer {
+	return &recorder{
+		base:   r.base,
+		path:   append(r.path[:len(r.path):len(r.path)], typedStep{t, s}),
+		parent: r,
+		recs:   r.recs,
+		stop:   r.stop,
+	}
+}
+
+func (r *recorder) didEmit() bool {
+	return r.did
+}
+
+func (r *recorder) steps() []step {
+	steps := r.base[:len(r.base):len(r.base)]
+	for _, ts := range r.path {
+		steps = append(steps, ts.s)
+	}
+	return steps
+}
+
+// replay sends rec to e.
+func (rec record) replay(e emitfer) {
+	for _, ts := range rec.path {
+		e = e.sub(ts.t, ts.s)
+	}
+	if rec.note {
+		e.notef(rec.format, rec.arg...)
+	} else {
+		e.emitf(rec.av, rec.bv, rec.format, rec.arg...)
+	}
+}
+
+// showsNotes reports whether notes appear in the output
+// described by c. Where they don't, AggregateRepeats
+// has no effect, so that every difference is still reported.
+func (c *config) showsNotes() bool {
+	if c.change != nil || c.template != nil {
+		return false
+	}
+	switch c.level {
+	case auto, columns, tree:
+		return true
+	}
+	return false
+}
+
+// replayAggregated sends the records in r to e,
+// where r is the root recorder for a list.
+// Whenever min or more elements of the list have
+// an identical difference at the same relative path,
+// it replaces them with a single note at that path,
+// with the element index written as a wildcard.
+// The note carries no values, since no one element's
+// values stand for the others.
+func (r *recorder) replayAggregated(e emitfer, min int) {
+	type group struct {
+		n    int
+		done bool
+	}
+	groups := map[string]*group{}
+	keys := make([]string, len(*r.recs))
+	for i, rec := range *r.recs {
+		if rec.note || len(rec.path) == 0 || rec.path[0].s.kind != stepIndex {
+			continue
+		}
+		keys[i] = rec.relPath() + "\x00" + fmt.Sprintf(rec.format, rec.arg...)
+		g := groups[keys[i]]
+		if g == nil {
+			g = &group{}
+			groups[keys[i]] = g
+		}
+		g.n++
+	}
+	for i, rec := range *r.recs {
+		g := groups[keys[i]]
+		if keys[i] == "" || g.n < min {
+			rec.replay(e)
+			continue
+		}
+		if g.done {
+			continue
+		}
+		g.done = true
+		s := e.sub(rec.path[0].t, step{kind: stepWildcard})
+		for _, ts := range rec.path[1:] {
+			s = s.sub(ts.t, ts.s)
+		}
+		if pe, ok := s.(*printEmitter); ok {
+			pe.markDiff()
+		}
+		msg := fmt.Sprintf(rec.format, rec.arg...)
+		s.notef("%d elements differ: %s", g.n, msg)
+	}
+}
+
+// relPath returns the path of rec below the list element
+// that contains it, for grouping records in replayAggregated.
+func (rec record) relPath() string {
+	steps := make([]step, len(rec.path)-1)
+	for i, ts := range rec.path[1:] {
+		steps[i] = ts.s
+	}
+	return formatPath(pathGo, steps)
+}
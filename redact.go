@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// redacted is written in place of a hidden value.
+const redacted = "[REDACTED]"
+
+// A display holds options that change how values
+// are shown in output, but not how they are compared.
+type display struct {
+	redact map[string]bool // field names, see Redact
+
+	hook func(Path, reflect.Value) (string, bool) // see Display
+
+	maxTypeLen int // see MaxTypeLen
+}
+
+// typeLimit returns the limit for writing types in short form.
+// It is safe to call on a nil *display.
+func (p *display) typeLimit() int {
+	if p == nil {
+		return maxAnonType
+	}
+	return p.maxTypeLen
+}
+
+// redacted reports whether the value of field i
+// of struct type t is to be hidden, by Redact or
+// by its tag. It is safe to call on a nil *display.
+func (p *display) redacted(t reflect.Type, i int) bool {
+	if p != nil && p.redact[t.Field(i).Name] {
+		return true
+	}
+	return structTags(t)[i].redact
+}
+
+// redactValue returns a value standing in for v, of a
+// redacted field, so every form of output, including
+// the full form, templates, and Change values,
+// shows it as [REDACTED].
+func redactValue(v reflect.Value) reflect.Value {
+	return reflect.ValueOf(reportValue{redacted, typeString(v)})
+}
+
+// locate records path p as the location of the values
+// in any formatters in arg, for the Display hook.
+func locate(arg []any, p Path) {
+	for _, a := range arg {
+		switch a := a.(type) {
+		case *formatter:
+			a.path = &p
+		case qualified:
+			locate([]any{a.Formatter}, p)
+		case note:
+			locate([]any{a.Formatter}, p)
+		}
+	}
+}
+
+// displayed calls the Display hook for av and bv at
+// the location of e. If the hook returns a display string
+// for either one, displayed emits a difference using
+// those strings, unless av and bv are equal, and reports
+// true, since there is nothing more to do at this path.
+func (d *differ) displayed(e emitfer, av, bv reflect.Value) bool {
+	d.config.helper()
+	p := d.pathAt(e)
+	as, aok := d.config.display.hook(p, av)
+	bs, bok := d.config.display.hook(p, bv)
+	if !aok && !bok {
+		return false
+	}
+	if d.equal(av, bv) {
+		return true
+	}
+	if !aok {
+		as = fmt.Sprint(formatShort(&d.config.display, av, false))
+	}
+	if !bok {
+		bs = fmt.Sprint(formatShort(&d.config.display, bv, false))
+	}
+	e.emitf(av, bv, "%s != %s", as, bs)
+	return true
+}
@@ -0,0 +1,198 @@
+package diff_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+
+	"kr.dev/diff"
+)
+
+func TestRedact(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string
+		Token    string `diff:"redact"`
+	}
+	type Key struct {
+		Secret string
+		N      int
+	}
+	cases := []struct {
+		name string
+		a, b any
+		opt  []diff.Option
+		want string
+	}{
+		{"equal", Login{"u", "p", "t"}, Login{"u", "p", "t"}, []diff.Option{diff.Redact("Password")}, ""},
+		{"name", Login{"u", "p", "t"}, Login{"u", "q", "t"}, []diff.Option{diff.Redact("Password")},
+			"diff_test.Login.Password: [REDACTED] != [REDACTED]\n"},
+		{"tag", Login{"u", "p", "t"}, Login{"u", "p", "s"}, nil,
+			"diff_test.Login.Token: [REDACTED] != [REDACTED]\n"},
+		{"not redacted", Login{"u", "p", "t"}, Login{"u", "q", "t"}, nil,
+			"diff_test.Login.Password: \"p\" != \"q\"\n"},
+		{"parent", &Key{"k", 1}, (*Key)(nil), []diff.Option{diff.Redact("Secret")},
+			"&diff_test.Key{Secret:[REDACTED], ...} != (*diff_test.Key)(nil)\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactFull(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	a := &Login{"u", "secret1"}
+	diff.Each(gotp.Printf, a, (*Login)(nil), diff.EmitFull, diff.Redact("Password"))
+	if strings.Contains(got, "secret1") || !strings.Contains(got, "Password: [REDACTED]") {
+		t.Errorf("EmitFull output doesn't hide the password:\n%s", got)
+	}
+}
+
+func TestRedactField(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string
+	}
+	a := Login{"u", "secret1"}
+	b := Login{"u", "secret2"}
+	cases := []struct {
+		name string
+		opt  diff.Option
+	}{
+		{"EmitFull", diff.EmitFull},
+		{"FullValues", diff.FullValues(true)},
+		{"Template", diff.Template(template.Must(template.New("t").Parse("{{.A}} {{.B}} {{index .Types 0}}")))},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, a, b, tt.opt, diff.Redact("Password"))
+			if strings.Contains(got, "secret") || !strings.Contains(got, "[REDACTED]") {
+				t.Errorf("output doesn't hide the password:\n%s", got)
+			}
+		})
+	}
+
+	changes := diff.New(diff.Redact("Password")).Compare(a, b)
+	if len(changes) != 1 {
+		t.Fatalf("Compare: got %d changes, want 1", len(changes))
+	}
+	if ch := changes[0]; fmt.Sprint(ch.A, " ", ch.B) != "[REDACTED] [REDACTED]" {
+		t.Errorf("Compare: A, B = %v, %v, want [REDACTED]", ch.A, ch.B)
+	}
+}
+
+func TestRedactSideBySide(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	a, b := Login{"u", "secret1"}, Login{"v", "secret2"}
+	diff.Each(gotp.Printf, a, b, diff.EmitSideBySide, diff.Redact("Password"))
+	if strings.Contains(got, "secret") || !strings.Contains(got, "[REDACTED] | [REDACTED]") {
+		t.Errorf("EmitSideBySide output doesn't hide the password:\n%s", got)
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	type Msg struct {
+		Body string
+		ID   int
+	}
+	body := func(p diff.Path, v reflect.Value) (string, bool) {
+		if strings.HasSuffix(p.String(), ".Body") {
+			return fmt.Sprintf("<%d bytes>", v.Len()), true
+		}
+		return "", false
+	}
+	cases := []struct {
+		name string
+		a, b any
+		opt  []diff.Option
+		want string
+	}{
+		{"equal", Msg{"abc", 1}, Msg{"abc", 1}, nil, ""},
+		{"hooked", Msg{"abc", 1}, Msg{"abcd", 1}, nil,
+			"diff_test.Msg.Body: <3 bytes> != <4 bytes>\n"},
+		{"not hooked", Msg{"abc", 1}, Msg{"abc", 2}, nil,
+			"diff_test.Msg.ID: 1 != 2\n"},
+		{"nested", map[string]Msg{}, map[string]Msg{"k": {"abc", 1}}, nil,
+			"map[string]diff_test.Msg[\"k\"]: (added) {Body:<3 bytes>, ...}\n"},
+		{"full", &Msg{"abc", 1}, (*Msg)(nil), []diff.Option{diff.EmitFull},
+			"a:\n" +
+				tab + "&diff_test.Msg{\n" +
+				tab + tab + "Body: <3 bytes>,\n" +
+				tab + tab + "ID:   1,\n" +
+				tab + "}\n" +
+				"b:\n" +
+				tab + "(*diff_test.Msg)(nil)\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, append(tt.opt, diff.Display(body))...)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func TestDisplayRecorded(t *testing.T) {
+	type Creds struct{ User, Pass string }
+	type T struct {
+		Creds Creds
+		Token string `diff:"redact"`
+	}
+	hide := diff.Display(func(p diff.Path, v reflect.Value) (string, bool) {
+		if strings.HasSuffix(p.String(), ".Creds") {
+			return "<hidden>", true
+		}
+		return "", false
+	})
+	a := []T{{Creds{"u", "secret1"}, "token1"}}
+	b := []T{{Creds{"u", "secret2"}, "token2"}}
+	for _, opt := range []struct {
+		name string
+		opt  diff.Option
+	}{
+		{"AggregateRepeats", diff.AggregateRepeats(5)},
+		{"ContainerSummary", diff.ContainerSummary(true)},
+		{"Similarity", diff.Similarity(true)},
+	} {
+		t.Run(opt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, a, b, hide, opt.opt)
+			if strings.Contains(got, "secret") || strings.Contains(got, "token") {
+				t.Errorf("output leaks a hidden value:\n%s", got)
+			}
+			for _, want := range []string{
+				"[]diff_test.T[0].Creds: <hidden> != <hidden>\n",
+				"[]diff_test.T[0].Token: [REDACTED] != [REDACTED]\n",
+			} {
+				if !strings.Contains(got, want) {
+					t.Errorf("got:\n%s\nwant a line %q", got, want)
+				}
+			}
+		})
+	}
+}
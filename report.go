@@ -0,0 +1,210 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"kr.dev/diff/internal/indent"
+)
+
+// A Report is a record of the differences found by
+// a comparison, made by Differ.Report. Unlike Change,
+// it holds only strings and numbers, so it can be
+// encoded with encoding/json or encoding/gob,
+// sent to another process, and written out later
+// using Report.Each.
+type Report struct {
+	// Root is the type of the values compared,
+	// in Go syntax, or "" if it's not known.
+	Root  string       `json:"root,omitempty"`
+	Diffs []ReportDiff `json:"diffs"`
+}
+
+// A ReportDiff is one difference in a Report.
+type ReportDiff struct {
+	Path []ReportStep `json:"path"` // from the root to the difference
+
+	// A and B hold the values in short form, as in EmitAuto,
+	// and AType and BType hold their types in Go syntax.
+	// All are "" if the value is absent on that side,
+	// such as for a map entry that was added or removed.
+	A     string `json:"a,omitempty"`
+	B     string `json:"b,omitempty"`
+	AType string `json:"aType,omitempty"`
+	BType string `json:"bType,omitempty"`
+
+	Text string `json:"text"` // the difference, as in Change
+}
+
+// A ReportStep is one step in the path to a difference.
+type ReportStep struct {
+	// Kind is "field", "index", "key", "range", "slice",
+	// "pair", or "wildcard".
+	Kind string `json:"kind"`
+
+	// Name is the name of a field in Go notation,
+	// or a map key in Go syntax.
+	// JSON is the name of a field or map key
+	// in JSON notation. See PathJQ.
+	Name string `json:"name,omitempty"`
+	JSON string `json:"json,omitempty"`
+
+	// I and J are the indexes of an element or range.
+	I int `json:"i,omitempty"`
+	J int `json:"j,omitempty"`
+}
+
+var stepKinds = [...]string{
+	stepIndex:    "index",
+	stepField:    "field",
+	stepKey:      "key",
+	stepRange:    "range",
+	stepSlice:    "slice",
+	stepWildcard: "wildcard",
+	stepPair:     "pair",
+}
+
+// Report compares values a and b and returns
+// a record of the differences it finds.
+// Output options, such as EmitFull, have no effect.
+func (df *Differ) Report(a, b any) Report {
+	var r Report
+	c := df.config
+	c.change = func(ch Change) {
+		if r.Root == "" && ch.Path.root != nil {
+			r.Root = typeString(reflect.Zero(ch.Path.root))
+		}
+		rd := ReportDiff{Text: ch.Text}
+		for _, s := range ch.Path.steps {
+			rd.Path = append(rd.Path, reportStep(s))
+		}
+		rd.A, rd.AType = shortValue(&df.config.display, ch.A)
+		rd.B, rd.BType = shortValue(&df.config.display, ch.B)
+		r.Diffs = append(r.Diffs, rd)
+	}
+	newDifferConfig(c).each(a, b)
+	return r
+}
+
+// shortValue returns the short form of x and its type,
+// or empty strings if x is nil.
+func shortValue(disp *display, x any) (s, typ string) {
+	if x == nil {
+		return "", ""
+	}
+	v := reflect.ValueOf(x)
+	if rv, ok := asReportValue(v); ok {
+		return rv.text, rv.typ // redacted
+	}
+	return fmt.Sprint(formatShort(disp, v, false)), typeString(v)
+}
+
+func reportStep(s step) ReportStep {
+	rs := ReportStep{Kind: stepKinds[s.kind], I: s.i, J: s.j}
+	switch s.kind {
+	case stepField:
+		rs.Name, rs.JSON = s.goName(), s.jsonName()
+	case stepKey:
+		rs.Name, rs.JSON = fmt.Sprintf("%#v", s.key), keyString(s.key)
+	}
+	return rs
+}
+
+// step returns rs as a step that writes the same path.
+// A field or key is made up of rs's names,
+// since the original field or key is gone.
+func (rs ReportStep) step() step {
+	for k, name := range stepKinds {
+		if name != rs.Kind {
+			continue
+		}
+		s := step{kind: stepKind(k), i: rs.I, j: rs.J}
+		switch s.kind {
+		case stepField:
+			s.field = reflect.StructField{
+				Name: rs.Name,
+				Tag:  reflect.StructTag(fmt.Sprintf("json:%q", rs.JSON)),
+			}
+		case stepKey:
+			s.key = reflect.ValueOf(reportKey{rs.Name, rs.JSON})
+		}
+		return s
+	}
+	panic(fmt.Sprintf("diff: bad ReportStep kind %q", rs.Kind))
+}
+
+// A reportKey is a map key from a Report.
+// It writes itself as its names in a path.
+type reportKey struct{ name, json string }
+
+func (k reportKey) GoString() string { return k.name }
+func (k reportKey) String() string   { return k.json }
+
+// Each writes the differences in r, calling f for each one,
+// the same way as the package-level function Each.
+// Output options, such as EmitColumns or PathJQ,
+// choose how they're written; others have no effect.
+// Values are written in their short form, even by EmitFull.
+// EmitSideBySide and EmitDOT, which show whole values,
+// are treated as EmitAuto.
+func (r Report) Each(f func(format string, arg ...any) (int, error), opt ...Option) {
+	c := newConfig(opt...)
+	c.sink = func(format string, arg ...any) { f(format, arg...) }
+	if c.level == sideBySide || c.level == dotGraph {
+		c.level = auto
+	}
+	e := newDifferConfig(c).rootEmitter()
+	if c.pathStyle == pathGo {
+		e.rootType = r.Root
+	}
+	for _, rd := range r.Diffs {
+		var sub emitfer = e
+		for _, s := range rd.Path {
+			sub = sub.sub(nil, s.step())
+		}
+		av, bv := rd.values()
+		if rd.AType != "" && rd.BType != "" && rd.Text == rd.A+" != "+rd.B {
+			sub.emitf(av, bv, "%v != %v", rd.A, rd.B)
+		} else {
+			sub.emitf(av, bv, "%s", rd.Text)
+		}
+	}
+	e.flush()
+}
+
+// values returns the values of rd for an emitter,
+// or the zero Value for a value that's absent.
+func (rd ReportDiff) values() (av, bv reflect.Value) {
+	if rd.AType != "" {
+		av = reflect.ValueOf(reportValue{rd.A, rd.AType})
+	}
+	if rd.BType != "" {
+		bv = reflect.ValueOf(reportValue{rd.B, rd.BType})
+	}
+	return av, bv
+}
+
+// A reportValue is a value from a Report, a redacted value,
+// or an elided string, which is only known by its short form
+// and type.
+type reportValue struct{ text, typ string }
+
+// Format writes the short form of v. With the # flag,
+// as for the full form, it is indented like a full value.
+func (v reportValue) Format(f fmt.State, verb rune) {
+	var w io.Writer = f
+	if f.Flag('#') {
+		w = indent.New(w, tab)
+	}
+	io.WriteString(w, v.text)
+}
+
+// asReportValue returns the reportValue held in v, if any.
+func asReportValue(v reflect.Value) (reportValue, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return reportValue{}, false
+	}
+	rv, ok := v.Interface().(reportValue)
+	return rv, ok
+}
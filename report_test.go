@@ -0,0 +1,109 @@
+package diff_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestReport(t *testing.T) {
+	type Item struct {
+		Name  string `json:"name"`
+		Price int    `json:"price"`
+	}
+	type T struct {
+		Items []Item          `json:"items"`
+		Tags  map[string]bool `json:"tags"`
+		Note  string          `json:"note"`
+	}
+	a := T{
+		Items: []Item{{"a", 1}, {"b", 2}},
+		Tags:  map[string]bool{"x": true, "a/b": true},
+		Note:  "1\n2\n",
+	}
+	b := T{
+		Items: []Item{{"a", 1}, {"c", 3}},
+		Tags:  map[string]bool{"y": true, "a/b": false},
+		Note:  "1\n3\n",
+	}
+	r := diff.New().Report(a, b)
+
+	var jr diff.Report
+	buf, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf, &jr); err != nil {
+		t.Fatal(err)
+	}
+	var gr diff.Report
+	var gbuf bytes.Buffer
+	if err := gob.NewEncoder(&gbuf).Encode(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewDecoder(&gbuf).Decode(&gr); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []struct {
+		name string
+		opt  diff.Option
+	}{
+		{"auto", diff.EmitAuto},
+		{"columns", diff.EmitColumns},
+		{"tree", diff.EmitTree},
+		{"paths", diff.EmitPathOnly},
+		{"jq", diff.PathJQ},
+		{"pointer", diff.PathJSONPointer},
+		{"jsonlines", diff.EmitJSONLines},
+	}
+	for _, tt := range opts {
+		t.Run(tt.name, func(t *testing.T) {
+			var want string
+			diff.Each((*stringPrinter)(&want).Printf, a, b, tt.opt)
+			for _, rr := range []struct {
+				name string
+				r    diff.Report
+			}{{"direct", r}, {"json", jr}, {"gob", gr}} {
+				var got string
+				rr.r.Each((*stringPrinter)(&got).Printf, tt.opt)
+				if got != want {
+					t.Errorf("%s: got:\n%s\nwant:\n%s", rr.name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReportEqual(t *testing.T) {
+	r := diff.New().Report(1, 1)
+	if len(r.Diffs) != 0 {
+		t.Errorf("Diffs = %v, want none", r.Diffs)
+	}
+	var got string
+	r.Each((*stringPrinter)(&got).Printf)
+	if got != "" {
+		t.Errorf("Each wrote %q, want nothing", got)
+	}
+}
+
+func TestReportRedact(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string
+	}
+	r := diff.New(diff.Redact("Password")).Report(Login{"u", "secret1"}, Login{"u", "secret2"})
+	buf, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf, []byte("secret")) {
+		t.Errorf("Report leaks the password: %s", buf)
+	}
+	if len(r.Diffs) != 1 || r.Diffs[0].A != "[REDACTED]" || r.Diffs[0].B != "[REDACTED]" {
+		t.Errorf("Diffs = %+v, want A and B [REDACTED]", r.Diffs)
+	}
+}
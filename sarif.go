@@ -0,0 +1,158 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// SARIF is the Static Analysis Results Interchange Format,
+// version 2.1.0, an OASIS standard. Only the parts
+// EmitSARIF writes are declared here.
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations,omitempty"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties *sarifProps     `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysical `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogical `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysical struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogical struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+type sarifProps struct {
+	Kind string `json:"kind"` // changed, added, or removed
+	A    string `json:"a,omitempty"`
+	B    string `json:"b,omitempty"`
+}
+
+// A sarifOutput holds the results and notifications
+// written at the end of EmitSARIF output.
+type sarifOutput struct {
+	results []sarifResult
+	notes   []sarifNotification
+}
+
+// sarifRuleID is the rule of every result.
+const sarifRuleID = "diff/difference"
+
+// addSARIF records a difference as a result, for EmitSARIF.
+func (e *printEmitter) addSARIF(av, bv reflect.Value, format string, arg ...any) {
+	text := fmt.Sprintf(format, arg...)
+	p := e.pathString()
+	loc := sarifLocation{}
+	if p != "" {
+		text = p + ": " + text
+		loc.LogicalLocations = []sarifLogical{{FullyQualifiedName: p}}
+	}
+	if f := e.config.location.file; f != "" {
+		loc.PhysicalLocation = &sarifPhysical{ArtifactLocation: sarifArtifact{URI: f}}
+		if n := e.config.location.line; n > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{StartLine: n}
+		}
+	}
+	props := &sarifProps{Kind: changeKind(av, bv)}
+	props.A, _ = e.jsonValue(av)
+	props.B, _ = e.jsonValue(bv)
+	e.out.sarif.results = append(e.out.sarif.results, sarifResult{
+		RuleID:     sarifRuleID,
+		Level:      "error",
+		Message:    sarifMessage{text},
+		Locations:  []sarifLocation{loc},
+		Properties: props,
+	})
+}
+
+// log returns the SARIF log, indented,
+// followed by a newline.
+func (s *sarifOutput) log() string {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "kr.dev/diff",
+			InformationURI: "https://pkg.go.dev/kr.dev/diff",
+			Rules: []sarifRule{{
+				ID:               sarifRuleID,
+				ShortDescription: sarifMessage{"Values differ"},
+			}},
+		}},
+		Results: s.results,
+	}
+	if len(s.notes) > 0 {
+		run.Invocations = []sarifInvocation{{
+			ExecutionSuccessful:        true,
+			ToolExecutionNotifications: s.notes,
+		}}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}) // can't fail
+	return buf.String()
+}
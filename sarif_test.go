@@ -0,0 +1,160 @@
+package diff_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+// sarifLog holds the parts of a SARIF log the tests check.
+type sarifLog struct {
+	Version string
+	Runs    []struct {
+		Tool struct {
+			Driver struct {
+				Name  string
+				Rules []struct{ ID string }
+			}
+		}
+		Invocations []struct {
+			ToolExecutionNotifications []struct {
+				Level   string
+				Message struct{ Text string }
+			}
+		}
+		Results []struct {
+			RuleID    string
+			Level     string
+			Message   struct{ Text string }
+			Locations []struct {
+				PhysicalLocation *struct {
+					ArtifactLocation struct{ URI string }
+					Region           *struct{ StartLine int }
+				}
+				LogicalLocations []struct{ FullyQualifiedName string }
+			}
+			Properties struct{ Kind, A, B string }
+		}
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	type T struct {
+		Name string
+		Tags map[string]string
+	}
+	a := T{Name: "<x>", Tags: map[string]string{"k": "v"}}
+	b := T{Name: "y", Tags: map[string]string{"n": "w"}}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, a, b, diff.EmitSARIF, diff.Location("api/user_test.go", 42))
+	var log sarifLog
+	if err := json.Unmarshal([]byte(got), &log); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("version %q, %d runs, want 2.1.0, 1 run", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if d := run.Tool.Driver; d.Name != "kr.dev/diff" || len(d.Rules) != 1 || d.Rules[0].ID != "diff/difference" {
+		t.Errorf("driver = %+v", d)
+	}
+	want := []struct{ text, path, kind, a, b string }{
+		{`diff_test.T.Name: "<x>" != "y"`, "diff_test.T.Name", "changed", `"<x>"`, `"y"`},
+		{`diff_test.T.Tags["k"]: (removed)`, `diff_test.T.Tags["k"]`, "removed", `"v"`, ""},
+		{`diff_test.T.Tags["n"]: (added) "w"`, `diff_test.T.Tags["n"]`, "added", "", `"w"`},
+	}
+	if len(run.Results) != len(want) {
+		t.Fatalf("got %d results, want %d:\n%s", len(run.Results), len(want), got)
+	}
+	for i, r := range run.Results {
+		w := want[i]
+		if r.RuleID != "diff/difference" || r.Level != "error" {
+			t.Errorf("result %d: rule %q, level %q", i, r.RuleID, r.Level)
+		}
+		if r.Message.Text != w.text {
+			t.Errorf("result %d: message %q, want %q", i, r.Message.Text, w.text)
+		}
+		if p := r.Properties; p.Kind != w.kind || p.A != w.a || p.B != w.b {
+			t.Errorf("result %d: properties %+v, want kind %q, a %q, b %q", i, p, w.kind, w.a, w.b)
+		}
+		if len(r.Locations) != 1 {
+			t.Errorf("result %d: %d locations, want 1", i, len(r.Locations))
+			continue
+		}
+		loc := r.Locations[0]
+		if pl := loc.PhysicalLocation; pl == nil || pl.ArtifactLocation.URI != "api/user_test.go" || pl.Region == nil || pl.Region.StartLine != 42 {
+			t.Errorf("result %d: physical location %+v, want api/user_test.go:42", i, pl)
+		}
+		if ll := loc.LogicalLocations; len(ll) != 1 || ll[0].FullyQualifiedName != w.path {
+			t.Errorf("result %d: logical locations %+v, want %q", i, ll, w.path)
+		}
+	}
+}
+
+func TestSARIFEqual(t *testing.T) {
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, 1, 1, diff.EmitSARIF)
+	if got != "" {
+		t.Errorf("output = %q, want none", got)
+	}
+}
+
+func TestSARIFTruncated(t *testing.T) {
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, []int{0, 0}, []int{1, 1}, diff.EmitSARIF, diff.MaxDiffs(1))
+	var log sarifLog
+	if err := json.Unmarshal([]byte(got), &log); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	run := log.Runs[0]
+	if len(run.Results) != 1 || run.Results[0].Locations[0].PhysicalLocation != nil {
+		t.Errorf("want 1 result with no physical location:\n%s", got)
+	}
+	inv := run.Invocations
+	if len(inv) != 1 || len(inv[0].ToolExecutionNotifications) != 1 {
+		t.Fatalf("want 1 notification:\n%s", got)
+	}
+	n := inv[0].ToolExecutionNotifications[0]
+	if n.Level != "warning" || n.Message.Text != "output truncated, 1 more differences" {
+		t.Errorf("notification = %+v", n)
+	}
+}
+
+func TestLocationPanics(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		line int
+	}{{"", 1}, {"a.go", -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Location(%q, %d) did not panic", tt.file, tt.line)
+				}
+			}()
+			diff.Location(tt.file, tt.line)
+		}()
+	}
+}
+
+func TestSARIFRedact(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string
+	}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, Login{"u", "secret1"}, Login{"u", "secret2"},
+		diff.EmitSARIF, diff.Redact("Password"))
+	var log sarifLog
+	if err := json.Unmarshal([]byte(got), &log); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("SARIF output leaks the password:\n%s", got)
+	}
+	p := log.Runs[0].Results[0].Properties
+	if p.A != "[REDACTED]" || p.B != "[REDACTED]" {
+		t.Errorf("properties = %+v, want a and b [REDACTED]", p)
+	}
+}
@@ -0,0 +1,160 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A scope holds options that apply only
+// beneath a path. See At.
+type scope struct {
+	path []string // one element per step, as written by stepToken
+	opt  Option
+}
+
+// parseScope splits path into its steps,
+// in the form given by stepToken.
+// It panics if path is malformed.
+func parseScope(path string) []string {
+	var steps []string
+	s := path
+	if s != "" && s[0] != '.' && s[0] != '[' {
+		s = "." + s
+	}
+	for s != "" {
+		switch s[0] {
+		case '.':
+			n := strings.IndexAny(s[1:], ".[") + 1
+			if n == 0 {
+				n = len(s)
+			}
+			if n == 1 || !isIdent(s[1:n]) {
+				panic("diff: bad field in At path " + strconv.Quote(path))
+			}
+			steps = append(steps, s[:n])
+			s = s[n:]
+		case '[':
+			tok := s[1:]
+			if strings.HasPrefix(tok, `"`) {
+				q, err := strconv.QuotedPrefix(tok)
+				if err != nil {
+					panic("diff: bad key in At path " + strconv.Quote(path))
+				}
+				tok = q
+			} else if n := strings.IndexByte(tok, ']'); n > 0 {
+				tok = tok[:n]
+			} else {
+				panic("diff: bad index in At path " + strconv.Quote(path))
+			}
+			s = s[1+len(tok):]
+			if !strings.HasPrefix(s, "]") {
+				panic("diff: missing ] in At path " + strconv.Quote(path))
+			}
+			s = s[1:]
+			if q, err := strconv.Unquote(tok); err == nil {
+				tok = strconv.Quote(q)
+			}
+			steps = append(steps, "["+tok+"]")
+		default:
+			panic("diff: bad At path " + strconv.Quote(path))
+		}
+	}
+	return steps
+}
+
+// stepToken returns s as it's written in a path given to At.
+// Elements of a list paired by Unordered are
+// written with their index in a.
+func stepToken(s step) string {
+	if s.kind == stepPair {
+		return fmt.Sprintf("[%d]", s.i)
+	}
+	var b strings.Builder
+	s.writeTo(&b, pathGo)
+	return b.String()
+}
+
+// matches reports whether sc applies at exactly path.
+func (sc scope) matches(path []step) bool {
+	if len(path) != len(sc.path) {
+		return false
+	}
+	for i, s := range path {
+		if sc.path[i] == "[*]" {
+			switch s.kind {
+			case stepIndex, stepKey, stepPair:
+				continue
+			}
+			return false
+		}
+		if stepToken(s) != sc.path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scoped returns c with the options of any scopes
+// that apply at path. Each scope is removed
+// once applied, so it applies only once
+// even if path is reached more than once,
+// for example by way of a pointer.
+func (c config) scoped(path []step) config {
+	var match, rest []scope
+	for _, sc := range c.scopes {
+		if sc.matches(path) {
+			match = append(match, sc)
+		} else {
+			rest = append(rest, sc)
+		}
+	}
+	if len(match) == 0 {
+		return c
+	}
+	forked := c.format == nil // see fork
+	c = c.clone()
+	c.scopes = rest
+	for _, sc := range match {
+		sc.opt.apply(&c)
+	}
+
+	// Paths in nested scopes are relative to this one.
+	prefix := make([]string, len(path))
+	for i, s := range path {
+		prefix[i] = stepToken(s)
+	}
+	for i := len(rest); i < len(c.scopes); i++ {
+		sc := c.scopes[i]
+		sc.path = append(prefix[:len(prefix):len(prefix)], sc.path...)
+		c.scopes[i] = sc
+	}
+	if forked {
+		c.format = nil
+		c.trace = nil
+	}
+	return c
+}
+
+// clone returns a copy of c that can be changed
+// by options without affecting c.
+func (c config) clone() config {
+	c.xform = cloneMap(c.xform)
+	c.format = cloneMap(c.format)
+	c.unordered = cloneMap(c.unordered)
+	c.sliceSets = cloneMap(c.sliceSets)
+	c.normKeys = cloneMap(c.normKeys)
+	c.funcs = new(funcCache)
+	c.normalize = c.normalize[:len(c.normalize):len(c.normalize)]
+	c.scopes = c.scopes[:len(c.scopes):len(c.scopes)]
+	return c
+}
+
+func cloneMap[V any](m map[reflect.Type]V) map[reflect.Type]V {
+	m1 := make(map[reflect.Type]V, len(m))
+	for k, v := range m {
+		m1[k] = v
+	}
+	return m1
+}
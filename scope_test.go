@@ -0,0 +1,193 @@
+package diff_test
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestAt(t *testing.T) {
+	type Limits struct {
+		CPU, Mem float64
+	}
+	type Config struct {
+		Name   string
+		Limits Limits
+		Scale  float64
+	}
+	type T struct {
+		Config Config
+		Items  []Limits
+		Labels map[string]float64
+		Ptr    *Limits
+		Tags   []string
+	}
+	approx := diff.CompareKind(reflect.Float64, func(a, b reflect.Value) bool {
+		return math.Abs(a.Float()-b.Float()) < 0.01
+	})
+	a := T{
+		Config: Config{"x", Limits{1, 2}, 3},
+		Items:  []Limits{{1, 2}, {3, 4}},
+		Labels: map[string]float64{"cpu": 1, "mem": 2},
+		Ptr:    &Limits{1, 2},
+		Tags:   []string{"a", "b"},
+	}
+	b := T{
+		Config: Config{"x", Limits{1.001, 2.001}, 3.001},
+		Items:  []Limits{{1.001, 2}, {3, 4.001}},
+		Labels: map[string]float64{"cpu": 1.001, "mem": 2.001},
+		Ptr:    &Limits{1.001, 2},
+		Tags:   []string{"b", "a"},
+	}
+
+	cases := []struct {
+		name string
+		opt  []diff.Option
+		want []string
+	}{
+		{"field", []diff.Option{diff.At("Config.Limits", approx)}, []string{
+			"T.Config.Scale: 3 != 3.001",
+			"T.Items[0].CPU: 1 != 1.001",
+			"T.Items[1].Mem: 4 != 4.001",
+			`T.Labels["cpu"]: 1 != 1.001`,
+			`T.Labels["mem"]: 2 != 2.001`,
+			"T.Ptr.CPU: 1 != 1.001",
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"leading dot", []diff.Option{diff.At(".Config", approx)}, []string{
+			"T.Items[0].CPU: 1 != 1.001",
+			"T.Items[1].Mem: 4 != 4.001",
+			`T.Labels["cpu"]: 1 != 1.001`,
+			`T.Labels["mem"]: 2 != 2.001`,
+			"T.Ptr.CPU: 1 != 1.001",
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"index", []diff.Option{diff.At("Items[1]", approx), diff.At("Config", approx)}, []string{
+			"T.Items[0].CPU: 1 != 1.001",
+			`T.Labels["cpu"]: 1 != 1.001`,
+			`T.Labels["mem"]: 2 != 2.001`,
+			"T.Ptr.CPU: 1 != 1.001",
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"wildcard", []diff.Option{diff.At("Items[*]", approx), diff.At(`Labels[*]`, approx)}, []string{
+			"T.Config.Limits.CPU: 1 != 1.001",
+			"T.Config.Limits.Mem: 2 != 2.001",
+			"T.Config.Scale: 3 != 3.001",
+			"T.Ptr.CPU: 1 != 1.001",
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"key", []diff.Option{diff.At(`Labels["cpu"]`, approx), diff.At("Config", approx)}, []string{
+			"T.Items[0].CPU: 1 != 1.001",
+			"T.Items[1].Mem: 4 != 4.001",
+			`T.Labels["mem"]: 2 != 2.001`,
+			"T.Ptr.CPU: 1 != 1.001",
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"pointer", []diff.Option{diff.At("Ptr", approx), diff.At("Config", approx)}, []string{
+			"T.Items[0].CPU: 1 != 1.001",
+			"T.Items[1].Mem: 4 != 4.001",
+			`T.Labels["cpu"]: 1 != 1.001`,
+			`T.Labels["mem"]: 2 != 2.001`,
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"nested", []diff.Option{diff.At("Config", diff.At("Limits", approx))}, []string{
+			"T.Config.Scale: 3 != 3.001",
+			"T.Items[0].CPU: 1 != 1.001",
+			"T.Items[1].Mem: 4 != 4.001",
+			`T.Labels["cpu"]: 1 != 1.001`,
+			`T.Labels["mem"]: 2 != 2.001`,
+			"T.Ptr.CPU: 1 != 1.001",
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"root", []diff.Option{diff.At("", approx)}, []string{
+			"T.Tags[0]: \"a\" != \"b\"",
+			"T.Tags[1]: \"b\" != \"a\"",
+		}},
+		{"type option", []diff.Option{diff.At("", approx), diff.At("Tags", diff.SliceSet[[]string]())}, nil},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := eachLines(a, b, tt.opt...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestAtUnordered(t *testing.T) {
+	// Elements paired by Unordered are compared
+	// with the options for their own path.
+	type T struct {
+		A, B []float64
+	}
+	approx := diff.CompareKind(reflect.Float64, func(a, b reflect.Value) bool {
+		return math.Abs(a.Float()-b.Float()) < 0.01
+	})
+	a := T{[]float64{1, 2}, []float64{1, 2}}
+	b := T{[]float64{2.001, 1.001}, []float64{2.001, 1.001}}
+	got := eachLines(a, b, diff.Unordered[[]float64](), diff.At("A[*]", approx))
+	if len(got) == 0 {
+		t.Fatalf("no differences, want differences in B")
+	}
+	for _, line := range got {
+		if !strings.HasPrefix(line, "T.B") {
+			t.Errorf("unexpected difference %q", line)
+		}
+	}
+}
+
+func TestAtDoesNotLeak(t *testing.T) {
+	// Options in At must not change the options
+	// used elsewhere, even for maps held in the config.
+	type T struct {
+		A, B []string
+	}
+	a := T{[]string{"x", "y"}, []string{"x", "y"}}
+	b := T{[]string{"y", "x"}, []string{"y", "x"}}
+	got := eachLines(a, b, diff.At("A", diff.SliceSet[[]string]()))
+	want := []string{
+		`T.B[0]: "x" != "y"`,
+		`T.B[1]: "y" != "x"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAtBadPath(t *testing.T) {
+	for _, path := range []string{"A..B", "A[", "A[]", `A["x]`, "A[1]B", "1A"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("At(%q) didn't panic", path)
+				}
+			}()
+			diff.At(path)
+		}()
+	}
+}
+
+// eachLines returns the lines of output from Each,
+// without the package name of the root type.
+func eachLines(a, b any, opt ...diff.Option) []string {
+	var out string
+	diff.Each((*stringPrinter)(&out).Printf, a, b, opt...)
+	var lines []string
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if line != "" {
+			lines = append(lines, strings.TrimSuffix(strings.TrimPrefix(line, "diff_test."), "\n"))
+		}
+	}
+	return lines
+}
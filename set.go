@@ -0,0 +1,65 @@
+package diff
+
+import "reflect"
+
+// isSetType reports whether map type t is used as a set:
+// its values are bools or empty structs.
+func isSetType(t reflect.Type) bool {
+	switch elem := t.Elem(); elem.Kind() {
+	case reflect.Bool:
+		return true
+	case reflect.Struct:
+		return elem.NumField() == 0
+	}
+	return false
+}
+
+// isMember reports whether the map entry with value v
+// is a member of a set.
+func isMember(v reflect.Value) bool {
+	return v.IsValid() && (v.Kind() != reflect.Bool || v.Bool())
+}
+
+// walkSet compares maps av and bv of type t as sets of keys.
+func (d *differ) walkSet(e emitfer, t reflect.Type, av, bv reflect.Value) {
+	d.config.helper()
+	for _, k := range sortedKeys(av, bv) {
+		ina, inb := isMember(av.MapIndex(k)), isMember(bv.MapIndex(k))
+		switch {
+		case ina && !inb:
+			e.sub(t, keyStep(k)).emitf(av.MapIndex(k), bv.MapIndex(k), "(removed)")
+		case inb && !ina:
+			e.sub(t, keyStep(k)).emitf(av.MapIndex(k), bv.MapIndex(k), "(added)")
+		}
+	}
+	d.walkSelfUnequalKeys(e, t, av, bv)
+}
+
+// walkSliceSet compares slices av and bv of type t
+// as sets of unique elements.
+func (d *differ) walkSliceSet(e emitfer, t reflect.Type, av, bv reflect.Value) {
+	d.config.helper()
+	for i := 0; i < av.Len(); i++ {
+		ai := av.Index(i)
+		if !d.hasElem(av, i, ai) && !d.hasElem(bv, bv.Len(), ai) {
+			e.sub(t, indexStep(i)).emitf(ai, reflect.Value{}, "(removed) %v", formatShort(&d.config.display, ai, false))
+		}
+	}
+	for j := 0; j < bv.Len(); j++ {
+		bj := bv.Index(j)
+		if !d.hasElem(bv, j, bj) && !d.hasElem(av, av.Len(), bj) {
+			e.sub(t, indexStep(j)).emitf(reflect.Value{}, bj, "(added) %v", formatShort(&d.config.display, bj, false))
+		}
+	}
+}
+
+// hasElem reports whether any of the first n elements
+// of list is equal to v.
+func (d *differ) hasElem(list reflect.Value, n int, v reflect.Value) bool {
+	for i := 0; i < n; i++ {
+		if d.equalAt(indexStep(i), list.Index(i), v) {
+			return true
+		}
+	}
+	return false
+}
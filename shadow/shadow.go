@@ -0,0 +1,219 @@
+// Package shadow compares the results of a candidate
+// implementation with those of the primary one it is meant
+// to replace, such as while dark-launching a rewritten
+// service against the old one on live traffic.
+//
+// A Comparer samples and rate-limits the comparisons,
+// so they can run on a busy path, and collects statistics
+// about the differences it finds. It reports each
+// comparison to an optional callback and Metrics.
+package shadow
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"kr.dev/diff"
+)
+
+// A Comparer compares pairs of results, keeping
+// statistics about them.
+// A Comparer is safe for concurrent use.
+type Comparer struct {
+	opt     []diff.Option
+	differ  *diff.Differ
+	sample  float64
+	limit   int
+	per     time.Duration
+	onDiff  func(Mismatch)
+	metrics Metrics
+
+	collector *diff.Collector
+
+	mu      sync.Mutex
+	rand    *rand.Rand
+	window  time.Time // start of the current RateLimit interval
+	nwindow int       // comparisons made in the current interval
+	calls   int
+	sampled int
+	limited int
+}
+
+// A Mismatch describes a pair of results that differ.
+type Mismatch struct {
+	Primary, Candidate any
+	Changes            []diff.Change
+}
+
+// Metrics receives a record of each call to Compare,
+// for example to export it to a monitoring system.
+// Its methods may be called concurrently.
+type Metrics interface {
+	// Compared is called after each comparison made,
+	// with the number of differences found.
+	Compared(ndiff int)
+
+	// Skipped is called for each call to Compare
+	// that made no comparison, with the reason:
+	// "sampled" (see Sample) or "limited" (see RateLimit).
+	Skipped(reason string)
+}
+
+// Stats holds counts of the calls to Compare,
+// and statistics about the differences found,
+// as collected by a diff.Collector.
+type Stats struct {
+	Calls   int // calls to Compare
+	Sampled int // calls skipped by Sample
+	Limited int // calls skipped by RateLimit
+
+	diff.Stats
+}
+
+// Option values can be passed to New to configure a Comparer.
+type Option struct{ apply func(*Comparer) }
+
+// Sample sets the fraction of calls to Compare, from 0 to 1,
+// that make a comparison. The calls are chosen at random.
+// The default is 1, to compare every pair.
+// Sample panics if p is out of range.
+func Sample(p float64) Option {
+	if !(p >= 0 && p <= 1) {
+		panic("shadow: Sample out of range")
+	}
+	return Option{func(c *Comparer) {
+		c.sample = p
+	}}
+}
+
+// RateLimit limits the comparisons made to n in each
+// interval of length per. Calls to Compare beyond
+// the limit are skipped. The default is no limit.
+// RateLimit panics if n or per is not positive.
+func RateLimit(n int, per time.Duration) Option {
+	if n <= 0 || per <= 0 {
+		panic("shadow: RateLimit must be positive")
+	}
+	return Option{func(c *Comparer) {
+		c.limit, c.per = n, per
+	}}
+}
+
+// Diff sets the options used to compare each pair.
+// Output options have no effect.
+func Diff(opt ...diff.Option) Option {
+	return Option{func(c *Comparer) {
+		c.opt = append(c.opt, opt...)
+	}}
+}
+
+// OnDiff sets a function to call with each pair
+// found to differ. It's called synchronously, by Compare,
+// so a slow f slows down the caller.
+func OnDiff(f func(Mismatch)) Option {
+	return Option{func(c *Comparer) {
+		c.onDiff = f
+	}}
+}
+
+// Observe sets m to receive a record of each call to Compare.
+func Observe(m Metrics) Option {
+	return Option{func(c *Comparer) {
+		c.metrics = m
+	}}
+}
+
+// New returns a Comparer configured by opt.
+func New(opt ...Option) *Comparer {
+	c := &Comparer{
+		sample: 1,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, o := range opt {
+		if o.apply != nil {
+			o.apply(c)
+		}
+	}
+	c.differ = diff.New(c.opt...)
+	c.collector = diff.NewCollector()
+	return c
+}
+
+// Default is the Comparer used by the package-level
+// function Compare. It compares every pair, with
+// the default options.
+var Default = New()
+
+// Compare compares primary and candidate using Default.
+// See Comparer.Compare.
+func Compare(primary, candidate any, opt ...diff.Option) bool {
+	return Default.Compare(primary, candidate, opt...)
+}
+
+// Compare compares primary and candidate, unless the call
+// is skipped by Sample or RateLimit, and adds the result
+// to the statistics in c. If opt is given, it is added
+// to c's options for this comparison only.
+// Compare reports whether the pair was compared
+// and found to differ.
+func (c *Comparer) Compare(primary, candidate any, opt ...diff.Option) bool {
+	if reason := c.skip(); reason != "" {
+		if c.metrics != nil {
+			c.metrics.Skipped(reason)
+		}
+		return false
+	}
+	d := c.differ
+	if len(opt) > 0 {
+		d = diff.New(append(c.opt[:len(c.opt):len(c.opt)], opt...)...)
+	}
+	found := d.Compare(primary, candidate)
+	c.collector.Add(found)
+	if c.metrics != nil {
+		c.metrics.Compared(len(found))
+	}
+	if len(found) == 0 {
+		return false
+	}
+	if c.onDiff != nil {
+		c.onDiff(Mismatch{primary, candidate, found})
+	}
+	return true
+}
+
+// skip counts a call to Compare and returns the reason
+// to skip it, or "" to make the comparison.
+func (c *Comparer) skip() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.sample < 1 && c.rand.Float64() >= c.sample {
+		c.sampled++
+		return "sampled"
+	}
+	if c.limit > 0 {
+		now := time.Now()
+		if now.Sub(c.window) >= c.per {
+			c.window, c.nwindow = now, 0
+		}
+		if c.nwindow >= c.limit {
+			c.limited++
+			return "limited"
+		}
+		c.nwindow++
+	}
+	return ""
+}
+
+// Stats returns a copy of the statistics collected so far.
+func (c *Comparer) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Calls:   c.calls,
+		Sampled: c.sampled,
+		Limited: c.limited,
+		Stats:   c.collector.Stats(),
+	}
+}
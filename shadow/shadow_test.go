@@ -0,0 +1,141 @@
+package shadow_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"kr.dev/diff"
+	"kr.dev/diff/shadow"
+)
+
+type metrics struct {
+	mu       sync.Mutex
+	compared []int
+	skipped  []string
+}
+
+func (m *metrics) Compared(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compared = append(m.compared, n)
+}
+
+func (m *metrics) Skipped(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.skipped = append(m.skipped, reason)
+}
+
+func TestCompare(t *testing.T) {
+	type Resp struct {
+		Code int
+		Body string
+	}
+	var mismatches []shadow.Mismatch
+	m := new(metrics)
+	c := shadow.New(
+		shadow.OnDiff(func(mm shadow.Mismatch) { mismatches = append(mismatches, mm) }),
+		shadow.Observe(m),
+	)
+	if c.Compare(Resp{200, "ok"}, Resp{200, "ok"}) {
+		t.Errorf("equal pair reported as differing")
+	}
+	if !c.Compare(Resp{200, "ok"}, Resp{500, "error"}) {
+		t.Errorf("differing pair reported as equal")
+	}
+	if len(mismatches) != 1 || len(mismatches[0].Changes) != 2 {
+		t.Errorf("mismatches = %v, want one with two changes", mismatches)
+	}
+	diff.Test(t, t.Errorf, m.compared, []int{0, 2})
+
+	got := c.Stats()
+	want := shadow.Stats{
+		Calls: 2,
+		Stats: diff.Stats{
+			Comparisons: 2,
+			Differing:   1,
+			Differences: 2,
+			ByPath: map[string]int{
+				"shadow_test.Resp.Code": 1,
+				"shadow_test.Resp.Body": 1,
+			},
+			ByType: map[string]int{"int": 1, "string": 1},
+		},
+	}
+	diff.Test(t, t.Errorf, got, want)
+}
+
+func TestCompareOptions(t *testing.T) {
+	type Resp struct {
+		Body string
+		Time time.Time
+	}
+	c := shadow.New(shadow.Diff(diff.ZeroFields[Resp]("Time")))
+	now := time.Now()
+	if c.Compare(Resp{"a", now}, Resp{"a", now.Add(time.Second)}) {
+		t.Errorf("Diff options not used")
+	}
+	if c.Compare(Resp{"a", now}, Resp{"A", now}, diff.NormalizeStrings(func(s string) string {
+		return string(s[0] | 0x20)
+	})) {
+		t.Errorf("per-call options not used")
+	}
+	if !c.Compare(Resp{"a", now}, Resp{"A", now}) {
+		t.Errorf("per-call options kept after the call")
+	}
+}
+
+func TestSample(t *testing.T) {
+	m := new(metrics)
+	c := shadow.New(shadow.Sample(0), shadow.Observe(m))
+	for i := 0; i < 10; i++ {
+		if c.Compare(1, 2) {
+			t.Errorf("Sample(0) compared a pair")
+		}
+	}
+	if s := c.Stats(); s.Calls != 10 || s.Sampled != 10 || s.Comparisons != 0 {
+		t.Errorf("Stats = %+v, want 10 calls sampled out", s)
+	}
+	if len(m.skipped) != 10 || m.skipped[0] != "sampled" {
+		t.Errorf("skipped = %v, want 10 sampled", m.skipped)
+	}
+
+	c = shadow.New(shadow.Sample(0.5))
+	for i := 0; i < 1000; i++ {
+		c.Compare(1, 2)
+	}
+	if n := c.Stats().Comparisons; n < 400 || n > 600 {
+		t.Errorf("Sample(0.5) made %d of 1000 comparisons", n)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	m := new(metrics)
+	c := shadow.New(shadow.RateLimit(2, time.Hour), shadow.Observe(m))
+	for i := 0; i < 5; i++ {
+		c.Compare(1, 2)
+	}
+	if s := c.Stats(); s.Calls != 5 || s.Limited != 3 || s.Comparisons != 2 {
+		t.Errorf("Stats = %+v, want 2 compared and 3 limited", s)
+	}
+	diff.Test(t, t.Errorf, m.skipped, []string{"limited", "limited", "limited"})
+}
+
+func TestBadOptions(t *testing.T) {
+	for name, f := range map[string]func(){
+		"Sample(-1)":       func() { shadow.Sample(-1) },
+		"Sample(2)":        func() { shadow.Sample(2) },
+		"RateLimit(0, 1s)": func() { shadow.RateLimit(0, time.Second) },
+		"RateLimit(1, 0)":  func() { shadow.RateLimit(1, 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s didn't panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
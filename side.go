@@ -0,0 +1,260 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A sideTable accumulates output for EmitSideBySide
+// and EmitDOT.
+// It holds the two values being compared and the paths
+// of their differences, and renders them once the walk
+// is done.
+type sideTable struct {
+	a, b  reflect.Value
+	diffs []string // paths with differences
+}
+
+// A sideRow is one row of EmitSideBySide output:
+// a value at path in a, b, or both.
+// Only leaf values are shown, but EmitDOT
+// uses rows for the values that hold them too.
+type sideRow struct {
+	path   string
+	parent string // path of the value holding this one
+	label  string // last step of path, or the root type
+	leaf   bool
+	a, b   string
+	haveA  bool
+	haveB  bool
+}
+
+// lines returns the rows of s aligned into columns.
+// The mark between the columns follows sdiff:
+// "|" for a row that differs, "<" for a row only in a,
+// ">" for a row only in b, and a space otherwise.
+func (s *sideTable) lines(c config) []string {
+	rows := mergeRows(sideLeaves(c, s.a), sideLeaves(c, s.b))
+	tb := new(table)
+	tb.add("", c.aLabel, "", c.bLabel)
+	for _, r := range rows {
+		mark := " "
+		switch {
+		case !r.haveB:
+			mark = "<"
+		case !r.haveA:
+			mark = ">"
+		case s.differs(r.path), s.differsBelow(r.path):
+			mark = "|"
+		}
+		tb.add(r.path, r.a, mark, r.b)
+	}
+	lines := tb.lines()
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " ")
+	}
+	return lines
+}
+
+// differs reports whether path is at or below
+// any of the paths in s.diffs.
+func (s *sideTable) differs(path string) bool {
+	for _, p := range s.diffs {
+		if under(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// differsBelow reports whether any of the paths in s.diffs
+// is below path, as it is for a value shown as a whole,
+// such as one with a transform, whose difference was
+// found inside it.
+func (s *sideTable) differsBelow(path string) bool {
+	for _, p := range s.diffs {
+		if p != path && under(p, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// under reports whether path is at or below p.
+// The empty path is that of a difference in the root value,
+// so every path is below it.
+func under(path, p string) bool {
+	if p == "" {
+		return true
+	}
+	if !strings.HasPrefix(path, p) {
+		return false
+	}
+	return len(path) == len(p) || strings.ContainsRune(".[/", rune(path[len(p)]))
+}
+
+// sideLeaves returns a row for each leaf value in v,
+// in the order Walk visits them, with only one side set.
+// A leaf is a value with no values inside it to show,
+// such as a number or a nil pointer, or a value with
+// a transform or format func, which is shown as a whole.
+func sideLeaves(c config, v reflect.Value) []sideRow {
+	var rows []sideRow
+	for _, r := range sideNodes(c, v) {
+		if r.leaf {
+			rows = append(rows, r)
+		}
+	}
+	return rows
+}
+
+// sideNodes is like sideLeaves, but it returns a row for
+// each value in v, including those that hold other values.
+// Rows for values that hold others have a short
+// form of the value set, as in "{...}".
+func sideNodes(c config, v reflect.Value) []sideRow {
+	if !v.IsValid() {
+		return nil
+	}
+	var rows []sideRow
+	index := map[string]int{} // path to row, since pointers share a path
+	w := &walker{
+		style: c.pathStyle,
+		root:  v.Type(),
+		seen:  map[visit]bool{},
+	}
+	hidden := map[string]bool{} // paths of redacted fields
+	w.fn = func(p Path, v reflect.Value) bool {
+		path := p.String()
+		if hidden[path] {
+			rows = append(rows, sideRow{path: path, parent: parentPath(p), label: nodeLabel(p), leaf: true, a: redacted, haveA: true})
+			return false
+		}
+		f := formatShort(&c.display, v, false)
+		locate([]any{f}, p)
+		i, ok := index[path]
+		if !ok {
+			i = len(rows)
+			index[path] = i
+			rows = append(rows, sideRow{path: path, parent: parentPath(p), label: nodeLabel(p), a: fmt.Sprint(f), haveA: true})
+		}
+		if v.Kind() == reflect.Struct {
+			for _, fp := range structPlan(v.Type()) {
+				if c.display.redacted(v.Type(), fp.index) {
+					q := p
+					q.steps = appendStep(p.steps, fp.step)
+					hidden[q.String()] = true
+				}
+			}
+		}
+		if !isLeaf(c, v) {
+			return true
+		}
+		rows[i].leaf, rows[i].a = true, leafText(c, v, f)
+		return false
+	}
+	w.walk(nil, v)
+	return rows
+}
+
+// leafText returns the text of leaf value v,
+// whose short form is f.
+// A value with a format func is shown as the func describes
+// it: the part before " != " in its description of v
+// compared with itself. A value with a transform is shown
+// in the short form of the transformed value.
+func leafText(c config, v reflect.Value, f fmt.Formatter) string {
+	if !v.CanInterface() {
+		if !v.CanAddr() {
+			return fmt.Sprint(f)
+		}
+		v = access(v)
+	}
+	if ff, ok := c.formatFunc(v.Type()); ok {
+		s := reflectApply(ff, v, v).String()
+		if x, _, ok := strings.Cut(s, " != "); ok {
+			return x
+		}
+	}
+	if xf, ok := c.xformFunc(v.Type()); ok {
+		return fmt.Sprint(formatShort(&c.display, applyChain(xf, v), false))
+	}
+	return fmt.Sprint(f)
+}
+
+// parentPath returns the path of the value holding
+// the one at p, or "" if p is the root.
+func parentPath(p Path) string {
+	if len(p.steps) == 0 {
+		return ""
+	}
+	p.steps = p.steps[:len(p.steps)-1]
+	return p.String()
+}
+
+// nodeLabel returns the last step of p,
+// or the type of the root if p has no steps.
+func nodeLabel(p Path) string {
+	var b strings.Builder
+	if len(p.steps) == 0 {
+		writeTypeLimit(&b, p.root, maxAnonType)
+		return b.String()
+	}
+	p.steps[len(p.steps)-1].writeTo(&b, p.style)
+	return b.String()
+}
+
+func isLeaf(c config, v reflect.Value) bool {
+	t := v.Type()
+	if _, ok := c.xformFunc(t); ok {
+		return true
+	}
+	if _, ok := c.formatFunc(t); ok {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	case reflect.Array:
+		return t.Len() == 0
+	case reflect.Struct:
+		return len(structPlan(t)) == 0 || isAtomic(t) || isLock(t)
+	}
+	return true
+}
+
+// mergeRows merges the rows of a and b by path,
+// keeping the order of each.
+// Rows only in b are placed just before the next row
+// of b that is also in a, or at the end if there is none.
+func mergeRows(a, b []sideRow) []sideRow {
+	index := map[string]int{}
+	for i, r := range a {
+		index[r.path] = i
+	}
+	before := make([][]sideRow, len(a)+1) // rows only in b, to go before a[i]
+	var pending []sideRow
+	for _, r := range b {
+		i, ok := index[r.path]
+		if !ok {
+			r.a, r.b = "", r.a
+			r.haveA, r.haveB = false, true
+			pending = append(pending, r)
+			continue
+		}
+		a[i].b, a[i].haveB = r.a, true
+		a[i].leaf = a[i].leaf || r.leaf
+		before[i] = append(before[i], pending...)
+		pending = nil
+	}
+	before[len(a)] = pending
+	var rows []sideRow
+	for i, r := range a {
+		rows = append(rows, before[i]...)
+		rows = append(rows, r)
+	}
+	return append(rows, before[len(a)]...)
+}
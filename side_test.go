@@ -0,0 +1,90 @@
+package diff_test
+
+import (
+	"testing"
+	"time"
+
+	"kr.dev/diff"
+)
+
+func TestSideBySide(t *testing.T) {
+	type T struct {
+		Name string
+		ID   int
+		Tags map[string]string
+	}
+	a := T{Name: "x", ID: 1, Tags: map[string]string{"k": "v", "m": "z"}}
+	b := T{Name: "y", ID: 1, Tags: map[string]string{"m": "z", "n": "w"}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.EmitSideBySide)
+	want := "                      a     b\n" +
+		"diff_test.T.Name      \"x\" | \"y\"\n" +
+		"diff_test.T.ID        1     1\n" +
+		"diff_test.T.Tags[\"k\"] \"v\" <\n" +
+		"diff_test.T.Tags[\"m\"] \"z\"   \"z\"\n" +
+		"diff_test.T.Tags[\"n\"]     > \"w\"\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, a, diff.EmitSideBySide)
+	if got != "" {
+		t.Errorf("equal: got %q, want empty", got)
+	}
+}
+
+func TestSideBySideNested(t *testing.T) {
+	type Item struct{ N int }
+	type T struct{ P *Item }
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, T{}, T{&Item{3}}, diff.EmitSideBySide)
+	want := "                a     b\n" +
+		"diff_test.T.P   nil <\n" +
+		"diff_test.T.P.N     > 3\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestSideBySideFuncs(t *testing.T) {
+	type K struct{ C float64 }
+	type T struct {
+		At time.Time
+		K  K
+	}
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := T{t0, K{1.5}}
+	b := T{t0.Add(time.Second), K{2.5}}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	tr := diff.Transform(func(k K) any { return int(k.C) })
+	diff.Each(gotp.Printf, a, b, diff.EmitSideBySide, tr)
+	want := "               a                      b\n" +
+		"diff_test.T.At 2020-01-01T00:00:00Z | 2020-01-01T00:00:01Z\n" +
+		"diff_test.T.K  1                    | 2\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestSideBySideRoot(t *testing.T) {
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, 1, 2, diff.EmitSideBySide)
+	want := "    a   b\n" +
+		"int 1 | 2\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build go1.21
+
+package diff
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Slog compares values a and b, logging a record to logger
+// for each difference it finds, then a summary record.
+// Each difference is logged at level Info with the message
+// "difference" and these attributes:
+//
+//	path  the path to the difference, as in EmitAuto
+//	a, b  the values, in short form, if present
+//	text  the difference, as in Change
+//
+// The summary has the message "compared" and
+// the number of differences as attribute "differences".
+// If the comparison ran past its Timeout, the summary
+// also has the Timeout as attribute "timeout".
+// Output options, such as EmitFull, have no effect.
+// Slog is available only in builds with Go 1.21 or later,
+// which provides package log/slog.
+func Slog(logger *slog.Logger, a, b any, opt ...Option) {
+	ctx := context.Background()
+	c := newConfig(opt...)
+	n := 0
+	var d *differ
+	c.change = func(ch Change) {
+		if d.timedOut && len(ch.Path.steps) == 0 && ch.A == nil && ch.B == nil {
+			return // the timeout, logged in the summary
+		}
+		n++
+		attrs := []slog.Attr{slog.String("path", ch.Path.String())}
+		if ch.A != nil {
+			s, _ := shortValue(&c.display, ch.A)
+			attrs = append(attrs, slog.String("a", s))
+		}
+		if ch.B != nil {
+			s, _ := shortValue(&c.display, ch.B)
+			attrs = append(attrs, slog.String("b", s))
+		}
+		attrs = append(attrs, slog.String("text", ch.Text))
+		logger.LogAttrs(ctx, slog.LevelInfo, "difference", attrs...)
+	}
+	d = newDifferConfig(c)
+	d.each(a, b)
+	attrs := []slog.Attr{slog.Int("differences", n)}
+	if d.timedOut {
+		attrs = append(attrs, slog.Duration("timeout", c.timeout))
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "compared", attrs...)
+}
@@ -0,0 +1,76 @@
+//go:build go1.21
+
+package diff_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"kr.dev/diff"
+)
+
+func TestSlog(t *testing.T) {
+	type T struct {
+		Name string
+		Tags map[string]int
+	}
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	a := T{"a", map[string]int{"x": 1}}
+	b := T{"b", map[string]int{"y": 2}}
+	diff.Slog(slog.New(h), a, b)
+	want := `level=INFO msg=difference path=diff_test.T.Name a="\"a\"" b="\"b\"" text="\"a\" != \"b\""` + "\n" +
+		`level=INFO msg=difference path="diff_test.T.Tags[\"x\"]" a=1 text=(removed)` + "\n" +
+		`level=INFO msg=difference path="diff_test.T.Tags[\"y\"]" b=2 text="(added) 2"` + "\n" +
+		`level=INFO msg=compared differences=3` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("bad log")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestSlogTimeout(t *testing.T) {
+	a := make([]int, 100000)
+	b := make([]int, 100000)
+	for i := range b {
+		b[i] = i % 2
+	}
+	slow := diff.Transform(func(x int) any {
+		for t0 := time.Now(); time.Since(t0) < 10*time.Microsecond; {
+		}
+		return x
+	})
+	var buf bytes.Buffer
+	h := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	diff.Slog(slog.New(h), a, b, slow, diff.Timeout(10*time.Millisecond))
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	last := lines[len(lines)-1]
+	want := fmt.Sprintf("level=INFO msg=compared differences=%d timeout=10ms", len(lines)-1)
+	if last != want {
+		t.Errorf("last line = %q, want %q", last, want)
+	}
+	for _, line := range lines[:len(lines)-1] {
+		if strings.Contains(line, "timed out") {
+			t.Errorf("timeout logged as a difference: %q", line)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Stats holds counts of differences found by a Collector.
+type Stats struct {
+	Comparisons int // number of comparisons made
+	Differing   int // comparisons that found at least one difference
+	Differences int // total differences found
+
+	// ByPath counts differences under each path prefix.
+	// For example, a difference at T.Items[3].Name is
+	// counted for T.Items, T.Items[*], and T.Items[*].Name.
+	// Array and slice indexes are replaced with *
+	// so the number of distinct paths stays small.
+	ByPath map[string]int
+
+	// ByType counts differences by the type of the
+	// values that differ.
+	ByType map[string]int
+}
+
+// A Collector compares values and accumulates
+// statistics about the differences it finds,
+// instead of printing them.
+// It is useful for monitoring how often, and where,
+// two sources of data drift apart.
+//
+// A Collector is safe for concurrent use.
+// It satisfies the expvar.Var interface, so it can be
+// published directly with expvar.Publish.
+type Collector struct {
+	d *Differ
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewCollector returns a new Collector that
+// compares values using opt.
+func NewCollector(opt ...Option) *Collector {
+	return &Collector{
+		d: New(opt...),
+		stats: Stats{
+			ByPath: map[string]int{},
+			ByType: map[string]int{},
+		},
+	}
+}
+
+// Compare compares a and b and adds any differences
+// to the statistics in c.
+// It reports whether a and b are equal.
+func (c *Collector) Compare(a, b any) bool {
+	found := c.d.Compare(a, b)
+	c.Add(found)
+	return len(found) == 0
+}
+
+// Add adds the differences found by one comparison
+// made elsewhere, such as by Differ.Compare,
+// to the statistics in c.
+// If found is empty, it counts an equal comparison.
+func (c *Collector) Add(found []Change) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Comparisons++
+	if len(found) > 0 {
+		c.stats.Differing++
+	}
+	c.stats.Differences += len(found)
+	for _, ch := range found {
+		for _, p := range pathPrefixes(ch.Path) {
+			c.stats.ByPath[p]++
+		}
+		c.stats.ByType[changeType(ch)]++
+	}
+}
+
+// Stats returns a copy of the statistics collected so far.
+func (c *Collector) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.ByPath = make(map[string]int, len(c.stats.ByPath))
+	for k, v := range c.stats.ByPath {
+		s.ByPath[k] = v
+	}
+	s.ByType = make(map[string]int, len(c.stats.ByType))
+	for k, v := range c.stats.ByType {
+		s.ByType[k] = v
+	}
+	return s
+}
+
+// Reset clears the statistics collected so far.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = Stats{
+		ByPath: map[string]int{},
+		ByType: map[string]int{},
+	}
+}
+
+// String returns the statistics collected so far
+// encoded as JSON.
+func (c *Collector) String() string {
+	b, err := json.Marshal(c.Stats())
+	if err != nil {
+		panic(err) // can't happen
+	}
+	return string(b)
+}
+
+// pathPrefixes returns the non-empty prefixes of p,
+// with array and slice indexes replaced by *.
+func pathPrefixes(p Path) []string {
+	steps := make([]step, len(p.steps))
+	copy(steps, p.steps)
+	var prefixes []string
+	for i := range steps {
+		if steps[i].kind == stepIndex {
+			steps[i] = step{kind: stepWildcard}
+		}
+		q := p
+		q.steps = steps[:i+1]
+		prefixes = append(prefixes, q.String())
+	}
+	return prefixes
+}
+
+// changeType returns the type of the values in c.
+func changeType(c Change) string {
+	v := c.A
+	if v == nil {
+		v = c.B
+	}
+	if v == nil {
+		return "nil"
+	}
+	var b strings.Builder
+	writeType(&b, reflect.TypeOf(v))
+	return b.String()
+}
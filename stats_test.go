@@ -0,0 +1,64 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestCollector(t *testing.T) {
+	type Item struct{ N, M int }
+	type T struct {
+		Name  string
+		Items []Item
+	}
+	c := diff.NewCollector()
+	a := T{Name: "a", Items: []Item{{1, 1}, {2, 2}}}
+	b := T{Name: "a", Items: []Item{{1, 0}, {3, 2}}}
+	if c.Compare(a, b) {
+		t.Errorf("Compare(a, b) = true, want false")
+	}
+	if !c.Compare(a, a) {
+		t.Errorf("Compare(a, a) = false, want true")
+	}
+
+	want := diff.Stats{
+		Comparisons: 2,
+		Differing:   1,
+		Differences: 2,
+		ByPath: map[string]int{
+			"diff_test.T.Items":      2,
+			"diff_test.T.Items[*]":   2,
+			"diff_test.T.Items[*].M": 1,
+			"diff_test.T.Items[*].N": 1,
+		},
+		ByType: map[string]int{"int": 2},
+	}
+	diff.Test(t, t.Errorf, c.Stats(), want)
+
+	const wantJSON = `{"Comparisons":2,"Differing":1,"Differences":2,` +
+		`"ByPath":{"diff_test.T.Items":2,"diff_test.T.Items[*]":2,` +
+		`"diff_test.T.Items[*].M":1,"diff_test.T.Items[*].N":1},` +
+		`"ByType":{"int":2}}`
+	if got := c.String(); got != wantJSON {
+		t.Errorf("String() = %s, want %s", got, wantJSON)
+	}
+}
+
+func TestCollectorAdd(t *testing.T) {
+	type T struct{ A, B int }
+	c := diff.NewCollector()
+	c.Add(diff.New().Compare(T{1, 2}, T{0, 0}))
+	c.Add(nil)
+	want := diff.Stats{
+		Comparisons: 2,
+		Differing:   1,
+		Differences: 2,
+		ByPath: map[string]int{
+			"diff_test.T.A": 1,
+			"diff_test.T.B": 1,
+		},
+		ByType: map[string]int{"int": 2},
+	}
+	diff.Test(t, t.Errorf, c.Stats(), want)
+}
@@ -0,0 +1,68 @@
+package diff
+
+import "context"
+
+// A Change describes a single difference between two values.
+type Change struct {
+	Path Path // location of the difference
+
+	// A and B are the values at Path, or nil if absent
+	// on that side. The value of a field hidden by Redact,
+	// or by its tag, is replaced by one that prints
+	// as [REDACTED].
+	A, B any
+
+	Text string
+}
+
+// String returns c as it would be written by Each
+// using EmitAuto.
+func (c Change) String() string {
+	if p := c.Path.String(); len(c.Path.steps) > 0 {
+		return p + ": " + c.Text
+	}
+	return c.Text
+}
+
+// Stream compares values a and b in a new goroutine,
+// sending each difference it finds on the returned channel.
+// The channel is closed when the comparison is done.
+// If the comparison runs past its Timeout, the last
+// Change sent says so.
+//
+// If ctx is canceled, Stream stops comparing as soon as
+// possible and closes the channel. Callers that stop reading
+// before the channel is closed must cancel ctx, or the
+// goroutine will leak.
+//
+// The behavior can be adjusted by supplying Option values.
+// Output options, such as EmitFull, have no effect.
+func Stream(ctx context.Context, a, b any, opt ...Option) <-chan Change {
+	ch := make(chan Change)
+	var d *differ
+	d = newChangeDiffer(func(c Change) {
+		if ctx.Err() != nil {
+			d.stop = true
+			return
+		}
+		select {
+		case ch <- c:
+		case <-ctx.Done():
+			d.stop = true
+		}
+	}, opt...)
+	d.ctx = ctx
+	go func() {
+		defer close(ch)
+		d.each(a, b)
+	}()
+	return ch
+}
+
+// newChangeDiffer returns a differ that calls f
+// for each difference it finds.
+func newChangeDiffer(f func(Change), opt ...Option) *differ {
+	d := newDiffer(func() {}, func(string, ...any) {}, opt...)
+	d.config.change = f
+	return d
+}
@@ -0,0 +1,62 @@
+package diff_test
+
+import (
+	"context"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestStream(t *testing.T) {
+	type T struct{ A, B int }
+	a := []T{{1, 2}, {3, 4}}
+	b := []T{{1, 0}, {0, 4}}
+	var got []string
+	for c := range diff.Stream(context.Background(), a, b) {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"[]diff_test.T[0].B: 2 != 0",
+		"[]diff_test.T[1].A: 3 != 0",
+	}
+	diff.Test(t, t.Errorf, got, want)
+}
+
+func TestStreamCancel(t *testing.T) {
+	a := make([]int, 100)
+	b := make([]int, 100)
+	for i := range b {
+		b[i] = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := diff.Stream(ctx, a, b)
+	c := <-ch
+	if c.A != 0 || c.B != 1 {
+		t.Errorf("first change = %v, want 0 != 1", c)
+	}
+	cancel()
+	n := 0
+	for range ch {
+		n++
+	}
+	if n > 1 {
+		t.Errorf("got %d changes after cancel, want at most 1", n)
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	type T struct{ N int }
+	a := make([]T, 100000)
+	b := make([]T, 100000)
+	b[len(b)-1].N = 1
+	visited := 0
+	tr := diff.Transform(func(x T) any { visited++; return x.N })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for c := range diff.Stream(ctx, a, b, tr) {
+		t.Errorf("got change %v after cancel", c)
+	}
+	if visited > 1000 {
+		t.Errorf("visited %d elements after cancel, want at most 1000", visited)
+	}
+}
@@ -0,0 +1,80 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// summarizing reports whether c calls for a summary
+// of the elements of each list or map with differences,
+// by ContainerSummary or Similarity.
+func (c *config) summarizing() bool {
+	return c.containerSummary || c.similarity
+}
+
+// summarize notes the shape of the differences in r at e,
+// where r is the root recorder for the elements of a list
+// or the entries of a map, and alen and blen are the
+// lengths of the two values. For ContainerSummary, it gives
+// the lengths and the number of elements added, removed,
+// and changed, as in "len 10→12, 3 added, 1 removed,
+// 2 changed", and for Similarity, the share of elements
+// that are equal, as in "92% equal (2/25 differ)".
+// It notes nothing if r has no differences.
+func (r *recorder) summarize(e emitfer, c *config, alen, blen int) {
+	var added, removed, changed int
+	var last string
+	for _, rec := range *r.recs {
+		if rec.note || len(rec.path) == 0 {
+			continue
+		}
+		var b strings.Builder
+		rec.path[0].s.writeTo(&b, pathGo)
+		if b.String() == last {
+			continue // another difference in the same element
+		}
+		last = b.String()
+		switch {
+		case len(rec.path) > 1:
+			changed++
+		case !rec.av.IsValid():
+			added++
+		case !rec.bv.IsValid():
+			removed++
+		default:
+			changed++
+		}
+	}
+	ndiff := added + removed + changed
+	if ndiff == 0 {
+		return
+	}
+	var parts []string
+	if c.containerSummary {
+		s := fmt.Sprintf("len %d", alen)
+		if alen != blen {
+			s += fmt.Sprintf("→%d", blen)
+		}
+		parts = append(parts, s)
+		for _, p := range []struct {
+			n    int
+			verb string
+		}{{added, "added"}, {removed, "removed"}, {changed, "changed"}} {
+			if p.n > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", p.n, p.verb))
+			}
+		}
+	}
+	if c.similarity {
+		n := alen + added // elements in either value
+		parts = append(parts, fmt.Sprintf("%d%% equal (%d/%d differ)", (n-ndiff)*100/n, ndiff, n))
+	}
+	e.notef("%s", strings.Join(parts, ", "))
+}
+
+// replayAll sends the records in r to e, in order.
+func (r *recorder) replayAll(e emitfer) {
+	for _, rec := range *r.recs {
+		rec.replay(e)
+	}
+}
@@ -0,0 +1,48 @@
+package diff
+
+import (
+	"reflect"
+	"sync"
+)
+
+var lockTypes = map[reflect.Type]bool{
+	reflect.TypeOf(sync.Mutex{}):     true,
+	reflect.TypeOf(sync.RWMutex{}):   true,
+	reflect.TypeOf(sync.Once{}):      true,
+	reflect.TypeOf(sync.WaitGroup{}): true,
+}
+
+// isLock reports whether t is one of the
+// synchronization types in package sync
+// whose state is ignored by EqualLocks.
+func isLock(t reflect.Type) bool {
+	return lockTypes[t]
+}
+
+// isAtomic reports whether t is one of the types in
+// package sync/atomic, such as atomic.Int64 or
+// atomic.Pointer[T], that hold a value read with Load.
+func isAtomic(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.PkgPath() != "sync/atomic" {
+		return false
+	}
+	m, ok := reflect.PointerTo(t).MethodByName("Load")
+	return ok && m.Type.NumIn() == 1 && m.Type.NumOut() == 1
+}
+
+// atomicLoad returns the value held in v, an atomic
+// type as reported by isAtomic, by calling its Load method.
+// It reports false if v can't be used that way,
+// because it was obtained through unexported fields
+// and is not addressable.
+func atomicLoad(v reflect.Value) (reflect.Value, bool) {
+	if v.CanAddr() {
+		v = access(v)
+	} else if v.CanInterface() {
+		v = addressable(v)
+	} else {
+		return reflect.Value{}, false
+	}
+	x := v.Addr().MethodByName("Load").Call(nil)[0]
+	return addressable(x), true
+}
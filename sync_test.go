@@ -0,0 +1,30 @@
+package diff_test
+
+import (
+	"sync"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestEqualLocks(t *testing.T) {
+	type T struct {
+		Mu sync.Mutex
+		N  int
+	}
+	a, b := new(T), new(T)
+	a.Mu.Lock()
+	defer a.Mu.Unlock()
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b)
+	if got != "" {
+		t.Errorf("default: got %q, want empty", got)
+	}
+
+	diff.Each(gotp.Printf, a, b, diff.EqualLocks(false))
+	if got == "" {
+		t.Errorf("EqualLocks(false): got no difference")
+	}
+}
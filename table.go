@@ -0,0 +1,66 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// A table accumulates rows of output to be aligned
+// into columns once all rows are known.
+type table struct {
+	rows [][]string
+}
+
+func (tb *table) add(cell ...string) {
+	tb.rows = append(tb.rows, cell)
+}
+
+// lines returns the rows of tb, with cells padded
+// so that each column lines up.
+func (tb *table) lines() []string {
+	if len(tb.rows) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 8, 1, ' ', 0)
+	for _, row := range tb.rows {
+		tw.Write([]byte(strings.Join(row, "\t") + "\n"))
+	}
+	tw.Flush()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// splitNE formats a message of the form "x != y"
+// and returns its two operands separately.
+// It returns ok == false if format is not of that form.
+func splitNE(format string, arg []any) (x, y string, ok bool) {
+	const ne = " != "
+	i := strings.Index(format, ne)
+	if i < 0 || strings.LastIndex(format, ne) != i {
+		return "", "", false
+	}
+	n := countVerbs(format[:i])
+	if n > len(arg) {
+		return "", "", false
+	}
+	x = fmt.Sprintf(format[:i], arg[:n]...)
+	y = fmt.Sprintf(format[i+len(ne):], arg[n:]...)
+	return x, y, true
+}
+
+// countVerbs returns the number of formatting verbs in format.
+func countVerbs(format string) (n int) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+		n++
+	}
+	return n
+}
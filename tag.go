@@ -0,0 +1,98 @@
+package diff
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	reflectTime     = reflect.TypeOf(time.Time{})
+	reflectDuration = reflect.TypeOf(time.Duration(0))
+)
+
+// A fieldTag holds the options given in the diff
+// struct tag of a field.
+type fieldTag struct {
+	skip       bool // "-": don't compare the field
+	ignoreZero bool // "ignorezero": don't compare if either side is zero
+
+	approx float64       // "approx=x": floats within x are equal
+	delta  time.Duration // "delta=d": times or durations within d are equal
+
+	name string // "name=s": label for the field in paths
+
+	redact bool // "redact": hide the values in output
+}
+
+// parseTag parses the diff struct tag of f.
+// It ignores options it doesn't know, since other
+// packages use the diff tag too, and options whose
+// values are bad or don't suit the type of f.
+func parseTag(f reflect.StructField) (tag fieldTag) {
+	s, ok := f.Tag.Lookup("diff")
+	if !ok {
+		return tag
+	}
+	if s == "-" {
+		tag.skip = true
+		return tag
+	}
+	for _, opt := range strings.Split(s, ",") {
+		key, val, _ := strings.Cut(opt, "=")
+		switch key {
+		case "ignorezero":
+			tag.ignoreZero = true
+		case "approx":
+			if k := f.Type.Kind(); k != reflect.Float32 && k != reflect.Float64 {
+				break
+			}
+			x, err := strconv.ParseFloat(val, 64)
+			if err == nil && x >= 0 { // false for NaN
+				tag.approx = x
+			}
+		case "delta":
+			if f.Type != reflectTime && f.Type != reflectDuration {
+				break
+			}
+			d, err := time.ParseDuration(val)
+			if err == nil && d >= 0 {
+				tag.delta = d
+			}
+		case "name":
+			if val != "" {
+				tag.name = val
+			}
+		case "redact":
+			tag.redact = true
+		}
+	}
+	return tag
+}
+
+// within reports whether a and b, values of a field
+// with tag, are equal within the tag's tolerance.
+func (tag fieldTag) within(a, b reflect.Value) bool {
+	switch {
+	case tag.approx > 0:
+		return math.Abs(a.Float()-b.Float()) <= tag.approx
+	case tag.delta > 0 && a.Type() == reflectDuration:
+		return withinDuration(a, b, tag.delta)
+	case tag.delta > 0:
+		d := a.Interface().(time.Time).Sub(b.Interface().(time.Time))
+		return -tag.delta <= d && d <= tag.delta
+	}
+	return false
+}
+
+// withinDuration reports whether durations a and b
+// are no more than tol apart.
+func withinDuration(a, b reflect.Value, tol time.Duration) bool {
+	d := time.Duration(b.Int()) - time.Duration(a.Int())
+	if d < 0 {
+		d = -d
+	}
+	return d >= 0 && d <= tol // d < 0 if the subtraction overflowed
+}
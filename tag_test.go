@@ -0,0 +1,136 @@
+package diff_test
+
+import (
+	"testing"
+	"time"
+
+	"kr.dev/diff"
+)
+
+func TestTagSkip(t *testing.T) {
+	type T struct {
+		A int
+		B int `diff:"-"`
+		C int `diff:"ignorezero"`
+	}
+	cases := []struct {
+		a, b T
+		want string
+	}{
+		{T{1, 2, 3}, T{1, 5, 3}, ""},
+		{T{1, 2, 3}, T{1, 2, 0}, ""},
+		{T{1, 2, 0}, T{1, 2, 3}, ""},
+		{T{1, 2, 3}, T{1, 2, 4}, "diff_test.T.C: 3 != 4\n"},
+		{T{1, 2, 3}, T{2, 2, 3}, "diff_test.T.A: 1 != 2\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("Each(%+v, %+v) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTagUnknown(t *testing.T) {
+	type T struct {
+		ID int `diff:"id,identifier"`
+		A  int `diff:"ignorzero"`
+	}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, T{1, 0}, T{2, 3})
+	want := "diff_test.T.ID: 1 != 2\n" +
+		"diff_test.T.A: 0 != 3\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTagTolerance(t *testing.T) {
+	t0 := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	type T struct {
+		F float64       `diff:"approx=0.01"`
+		T time.Time     `diff:"delta=1s"`
+		D time.Duration `diff:"delta=1ms"`
+	}
+	cases := []struct {
+		a, b T
+		want string
+	}{
+		{T{1, t0, 0}, T{1.005, t0.Add(time.Second), time.Millisecond}, ""},
+		{T{1, t0, 0}, T{0.995, t0.Add(-time.Second), -time.Millisecond}, ""},
+		{T{1, t0, 0}, T{1.1, t0, 0}, "diff_test.T.F: 1 != 1.1\n"},
+		{T{1, t0, 0}, T{1, t0.Add(2 * time.Second), 0}, "diff_test.T.T: 2020-01-02T03:04:05Z != 2020-01-02T03:04:07Z (2s)\n"},
+		{T{1, t0, 0}, T{1, t0, 2 * time.Millisecond}, "diff_test.T.D: 0s != 2ms\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("Each(%+v, %+v) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTagToleranceBad(t *testing.T) {
+	type Int struct {
+		N int `diff:"approx=1"`
+	}
+	type NaN struct {
+		F float64 `diff:"approx=NaN"`
+	}
+	type Neg struct {
+		F float64 `diff:"approx=-1"`
+	}
+	type Str struct {
+		S string `diff:"delta=1s"`
+	}
+	type Name struct {
+		N int `diff:"name="`
+	}
+	cases := []struct {
+		name string
+		a, b any
+		want string
+	}{
+		{"int", Int{1}, Int{2}, "diff_test.Int.N: 1 != 2\n"},
+		{"NaN", NaN{1}, NaN{1.5}, "diff_test.NaN.F: 1 != 1.5\n"},
+		{"negative", Neg{1}, Neg{1.5}, "diff_test.Neg.F: 1 != 1.5\n"},
+		{"string", Str{"a"}, Str{"b"}, "diff_test.Str.S: \"a\" != \"b\"\n"},
+		{"name", Name{1}, Name{2}, "diff_test.Name.N: 1 != 2\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, tt.a, tt.b)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagName(t *testing.T) {
+	type T struct {
+		ID   int `json:"id" diff:"name=user_id"`
+		Name string
+	}
+	cases := []struct {
+		opt  diff.Option
+		want string
+	}{
+		{diff.PathGo, "diff_test.T.user_id: 1 != 2\n"},
+		{diff.PathJQ, ".user_id: 1 != 2\n"},
+		{diff.PathJSONPointer, "/user_id: 1 != 2\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Each(gotp.Printf, T{ID: 1}, T{ID: 2}, tt.opt)
+		if got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tapVersion is the first line of EmitTAP output.
+const tapVersion = "TAP version 13\n"
+
+// writeTAP writes a difference as a failed test point
+// with a YAML diagnostic block, for EmitTAP.
+func (e *printEmitter) writeTAP(av, bv reflect.Value, format string, arg ...any) {
+	// The version line doesn't count toward MaxBytes,
+	// like the plan, so the output is always valid TAP.
+	e.config.sink("%s", e.tapStart())
+	var b strings.Builder
+	p := e.pathString()
+	if p == "" {
+		p = "(root)"
+	}
+	fmt.Fprintf(&b, "not ok %d - %s\n", e.out.ndiff, tapDescription(p))
+	b.WriteString("  ---\n")
+	fmt.Fprintf(&b, "  message: %s\n", strconv.Quote(fmt.Sprintf(format, arg...)))
+	fmt.Fprintf(&b, "  kind: %s\n", changeKind(av, bv))
+	if a, _ := e.jsonValue(av); av.IsValid() {
+		fmt.Fprintf(&b, "  a: %s\n", strconv.Quote(a))
+	}
+	if s, _ := e.jsonValue(bv); bv.IsValid() {
+		fmt.Fprintf(&b, "  b: %s\n", strconv.Quote(s))
+	}
+	b.WriteString("  ...\n")
+	if !e.write(true, "%s", b.String()) {
+		// Number and plan only the points written.
+		e.out.ndiff--
+	}
+}
+
+// tapStart returns the version line the first time
+// it is called, and the empty string after that.
+func (e *printEmitter) tapStart() string {
+	if e.out.tapStarted {
+		return ""
+	}
+	e.out.tapStarted = true
+	return tapVersion
+}
+
+// tapPlan returns the end of EmitTAP output, given the
+// number of differences written: the plan, a passing
+// test point and the plan if there were no differences,
+// or Bail out! if there were, but none were written.
+func (e *printEmitter) tapPlan() string {
+	if n := e.out.ndiff; n > 0 {
+		return fmt.Sprintf("1..%d\n", n)
+	}
+	if e.out.dropped > 0 {
+		return e.tapStart() + "Bail out! differences exceed MaxBytes\n"
+	}
+	return e.tapStart() + "ok 1 - equal\n1..1\n"
+}
+
+// tapDescription returns s for use as the description
+// of a test point. It escapes # so s isn't read as a
+// directive, and writes s on one line.
+func tapDescription(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "#", `\#`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
@@ -0,0 +1,139 @@
+package diff_test
+
+import (
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestTAP(t *testing.T) {
+	type T struct {
+		Name string
+		Tags map[string]string
+	}
+	a := T{Name: "x", Tags: map[string]string{"k#1": "v"}}
+	b := T{Name: "y"}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, a, b, diff.EmitTAP)
+	want := `TAP version 13
+not ok 1 - diff_test.T.Name
+  ---
+  message: "\"x\" != \"y\""
+  kind: changed
+  a: "\"x\""
+  b: "\"y\""
+  ...
+not ok 2 - diff_test.T.Tags
+  ---
+  message: "{\"k#1\":\"v\"} != nil"
+  kind: changed
+  a: "{\"k#1\":\"v\"}"
+  b: "nil"
+  ...
+1..2
+`
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestTAPEqual(t *testing.T) {
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, 1, 1, diff.EmitTAP)
+	want := "TAP version 13\nok 1 - equal\n1..1\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTAPTruncated(t *testing.T) {
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, map[string]int{"a#b": 0, "c": 0}, map[string]int{"a#b": 1, "c": 1},
+		diff.EmitTAP, diff.MaxDiffs(1))
+	want := `TAP version 13
+not ok 1 - map[string]int["a\#b"]
+  ---
+  message: "0 != 1"
+  kind: changed
+  a: "0"
+  b: "1"
+  ...
+# output truncated, 1 more differences
+1..1
+`
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestTAPMaxBytes(t *testing.T) {
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, []string{"a", "b", "c"}, []string{"x", "y", "z"},
+		diff.EmitTAP, diff.MaxBytes(250))
+	want := `TAP version 13
+not ok 1 - []string[0]
+  ---
+  message: "\"a\" != \"x\""
+  kind: changed
+  a: "\"a\""
+  b: "\"x\""
+  ...
+not ok 2 - []string[1]
+  ---
+  message: "\"b\" != \"y\""
+  kind: changed
+  a: "\"b\""
+  b: "\"y\""
+  ...
+# output truncated, 1 more differences
+1..2
+`
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestTAPMaxBytesNone(t *testing.T) {
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf,
+		[]string{"aaaaaaaaaaaaaaaaaaaaaa", "b", "c"},
+		[]string{"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx", "y", "z"},
+		diff.EmitTAP, diff.MaxBytes(150))
+	want := "TAP version 13\n" +
+		"# output truncated, 3 more differences\n" +
+		"Bail out! differences exceed MaxBytes\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTAPRedact(t *testing.T) {
+	type Login struct {
+		User     string
+		Password string
+	}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, Login{"u", "secret1"}, Login{"u", "secret2"},
+		diff.EmitTAP, diff.Redact("Password"))
+	want := `TAP version 13
+not ok 1 - diff_test.Login.Password
+  ---
+  message: "[REDACTED] != [REDACTED]"
+  kind: changed
+  a: "[REDACTED]"
+  b: "[REDACTED]"
+  ...
+1..1
+`
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
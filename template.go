@@ -0,0 +1,79 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is the data passed to a template
+// set by Template, once for each difference.
+type TemplateData struct {
+	Change
+
+	// Types holds the types of A and B, written
+	// in Go syntax, or "" if the value is absent.
+	Types [2]string
+}
+
+// Template sets a template to write each difference,
+// in place of the output format selected by options
+// such as EmitAuto or EmitFull.
+// The template is executed with a TemplateData value,
+// for example:
+//
+//	tmpl := template.Must(template.New("").Parse(
+//		"{{.Path}}: got {{.A}}, want {{.B}}"))
+//	diff.Test(t, t.Errorf, got, want, diff.Template(tmpl))
+//
+// A newline is added to the output if it doesn't end
+// with one. If executing the template fails,
+// the error is written instead.
+// Template panics if t is nil.
+func Template(t *template.Template) Option {
+	if t == nil {
+		panic("diff: nil template")
+	}
+	return Option{func(c *config) {
+		c.template = t
+	}}
+}
+
+// writeTemplate writes a difference using the template
+// set by Template.
+func (e *printEmitter) writeTemplate(av, bv reflect.Value, format string, arg ...any) {
+	data := TemplateData{
+		Change: Change{
+			Path: Path{root: e.root, steps: e.steps(), style: e.config.pathStyle},
+			A:    valueInterface(av),
+			B:    valueInterface(bv),
+			Text: fmt.Sprintf(format, arg...),
+		},
+		Types: [2]string{typeString(av), typeString(bv)},
+	}
+	var b strings.Builder
+	if err := e.config.template.Execute(&b, data); err != nil {
+		b.Reset()
+		b.WriteString("diff: " + err.Error())
+	}
+	s := b.String()
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	e.write(true, "%s", s)
+}
+
+// typeString returns the type of v in Go syntax,
+// or "" if v is the zero Value.
+func typeString(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if rv, ok := asReportValue(v); ok {
+		return rv.typ
+	}
+	var b strings.Builder
+	writeType(&b, v.Type())
+	return b.String()
+}
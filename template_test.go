@@ -0,0 +1,42 @@
+package diff_test
+
+import (
+	"testing"
+	"text/template"
+
+	"kr.dev/diff"
+)
+
+func TestTemplate(t *testing.T) {
+	type T struct {
+		N int
+		V any
+		M map[string]int
+	}
+	a := T{N: 1, V: 1, M: map[string]int{"x": 1}}
+	b := T{N: 2, V: "1", M: map[string]int{}}
+	tmpl := template.Must(template.New("").Parse(
+		"{{.Path}}: got {{.A}} ({{index .Types 0}}), want {{.B}} ({{index .Types 1}})"))
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.Template(tmpl))
+	want := "diff_test.T.N: got 1 (int), want 2 (int)\n" +
+		"diff_test.T.V: got 1 (int), want 1 (string)\n" +
+		"diff_test.T.M[\"x\"]: got 1 (int), want <no value> ()\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestTemplateError(t *testing.T) {
+	tmpl := template.Must(template.New("t").Parse("{{.Nope}}"))
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, 1, 2, diff.Template(tmpl))
+	want := "diff: template: t:1:2: executing \"t\" at <.Nope>: can't evaluate field Nope in type diff.TemplateData\n"
+	if got != want {
+		t.Errorf("template error = %q, want %q", got, want)
+	}
+}
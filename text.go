@@ -1,29 +1,177 @@
 package diff
 
 import (
+	"bufio"
 	"context"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
-	"github.com/pkg/diff"
+	"github.com/pkg/diff/ctxt"
 	"github.com/pkg/diff/myers"
+	"github.com/pkg/diff/write"
 )
 
+// Strings compares strings a and b line by line,
+// calling f with a unified diff if they differ.
+// Unlike Each, it always produces a unified diff,
+// even for short or single-line strings.
+//
+// Options that affect text output, such as TextContext
+// and Prefix, apply as usual.
+func Strings(f func(format string, arg ...any) (int, error), a, b string, opt ...Option) {
+	fdis := func(format string, arg ...any) { f(format, arg...) }
+	d := newDiffer(func() {}, fdis, opt...)
+	d.unified(reflect.ValueOf(a), reflect.ValueOf(b), a, b)
+}
+
+// Bytes compares byte slices a and b,
+// calling f with a unified diff if they differ.
+// If both a and b look like text (valid UTF-8 with no
+// control characters other than tab, newline, and carriage
+// return), Bytes compares them line by line, like Strings.
+// Otherwise it compares hex dumps of them, in the format
+// of encoding/hex.Dump.
+func Bytes(f func(format string, arg ...any) (int, error), a, b []byte, opt ...Option) {
+	fdis := func(format string, arg ...any) { f(format, arg...) }
+	d := newDiffer(func() {}, fdis, opt...)
+	as, bs := string(a), string(b)
+	if !isText(a) || !isText(b) {
+		as, bs = hex.Dump(a), hex.Dump(b)
+	}
+	d.unified(reflect.ValueOf(a), reflect.ValueOf(b), as, bs)
+}
+
+// unified emits a unified diff of a and b, if they differ,
+// as the only difference between av and bv.
+func (d *differ) unified(av, bv reflect.Value, a, b string) {
+	a, b = d.normalized(a), d.normalized(b)
+	e := d.rootEmitter()
+	if a != b {
+		e.emitf(av, bv, "%s", &diffTextFormatter{
+			a:       a,
+			b:       b,
+			aLabel:  d.config.aLabel,
+			bLabel:  d.config.bLabel,
+			context: d.config.textContext,
+		})
+	}
+	e.flush()
+}
+
+const (
+	elideMin  = 64 // shortest common prefix or suffix to elide
+	elideKeep = 16 // bytes of it to keep next to the difference
+)
+
+// An elidedString is a string with a long prefix and suffix,
+// shared with another string, left out.
+type elidedString struct {
+	pre, suf int // number of bytes left out
+	s        string
+}
+
+// elide returns a and b with their common prefix and suffix
+// left out, if either is long enough to be worth leaving out.
+func elide(a, b string) (ea, eb elidedString, ok bool) {
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	s := 0
+	for s < len(a)-p && s < len(b)-p && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+	pre, suf := 0, 0
+	if p >= elideMin {
+		pre = p - elideKeep
+		for pre > 0 && !utf8.RuneStart(a[pre]) {
+			pre--
+		}
+	}
+	if s >= elideMin {
+		suf = s - elideKeep
+		for suf > 0 && !utf8.RuneStart(a[len(a)-suf]) {
+			suf--
+		}
+	}
+	ea = elidedString{pre, suf, a[pre : len(a)-suf]}
+	eb = elidedString{pre, suf, b[pre : len(b)-suf]}
+	return ea, eb, pre > 0 || suf > 0
+}
+
+// Format writes the string in quotes, with markers
+// in place of the parts left out, for example:
+//
+//	…[1420 bytes equal]…"abc"…[64 bytes equal]…
+func (es elidedString) Format(f fmt.State, verb rune) {
+	if es.pre > 0 {
+		fmt.Fprintf(f, "…[%d bytes equal]…", es.pre)
+	}
+	fmt.Fprintf(f, "%+q", es.s)
+	if es.suf > 0 {
+		fmt.Fprintf(f, "…[%d bytes equal]…", es.suf)
+	}
+}
+
+// normalized returns s after applying the normalization
+// options in d's config.
+func (d *differ) normalized(s string) string {
+	if d.config.crlf {
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+	}
+	for _, f := range d.config.normalize {
+		s = f(s)
+	}
+	return s
+}
+
+// isText reports whether p looks like text.
+func isText(p []byte) bool {
+	if !utf8.Valid(p) {
+		return false
+	}
+	for _, c := range p {
+		if c < ' ' && c != '\t' && c != '\n' && c != '\r' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
 func (d *differ) textDiff(e emitfer, av, bv reflect.Value, a, b string) {
 	d.config.helper()
 
 	// TODO(kr): check for whitespace-only changes, use special format
 
 	if d.config.level == full {
+		// Show the elided strings, quoted with the markers
+		// outside the quotes, in place of the originals,
+		// unless the originals are needed for a Change.
+		// This goes for []byte values too.
+		if ea, eb, ok := elide(a, b); ok && d.config.change == nil {
+			av = reflect.ValueOf(reportValue{fmt.Sprint(ea), typeString(av)})
+			bv = reflect.ValueOf(reportValue{fmt.Sprint(eb), typeString(bv)})
+		}
 		e.emitf(av, bv, "")
 		return
 	}
 
 	// Check for multi-line.
-	if textCheck(a, "\n", 2, 72) && textCheck(b, "\n", 2, 72) {
-		e.emitf(av, bv, "%s", &diffTextFormatter{a, b, d.config.aLabel, d.config.bLabel})
+	// If the lines are the same after splitting, the difference
+	// is in the line terminators, which a line diff can't show.
+	if textCheck(a, "\n", 2, 72) && textCheck(b, "\n", 2, 72) && !equalLines(a, b) {
+		e.emitf(av, bv, "%s", &diffTextFormatter{
+			a:       a,
+			b:       b,
+			aLabel:  d.config.aLabel,
+			bLabel:  d.config.bLabel,
+			context: d.config.textContext,
+		})
 		return
 	}
 
@@ -54,7 +202,7 @@ func textDiffInline(e emitfer, av, bv reflect.Value, a, b string, as, bs []strin
 	for _, ed := range merge(myers.Diff(context.Background(), pair)) {
 		a0, a1 := acut[ed.a0], acut[ed.a1]
 		b0, b1 := bcut[ed.b0], bcut[ed.b1]
-		ee := e.subf(reflectString, "[%d:%d]", a0, a1)
+		ee := e.sub(reflectString, sliceStep(a0, a1))
 		ee.emitf(av, bv, "%+q != %+q", a[a0:a1], b[b0:b1])
 	}
 }
@@ -64,10 +212,16 @@ func textCheck(s, sep string, nmin, amax int) bool {
 	return n >= nmin && len(s)/n <= amax
 }
 
-type diffTextFormatter struct{ a, b, aLabel, bLabel string }
+type diffTextFormatter struct {
+	a, b, aLabel, bLabel string
+
+	context int // lines of unchanged context around each hunk
+}
 
 func (df *diffTextFormatter) Format(f fmt.State, verb rune) {
-	err := diff.Text(df.aLabel, df.bLabel, df.a, df.b, f)
+	pair := &visibleLines{slicePair[string]{a: splitLines(df.a), b: splitLines(df.b)}}
+	script := ctxt.Size(myers.Diff(context.Background(), pair), df.context)
+	err := write.Unified(script, f, pair, write.Names(df.aLabel, df.bLabel))
 	if err != nil {
 		panic(err)
 	}
@@ -79,6 +233,49 @@ func (ab *slicePair[T]) LenA() int             { return len(ab.a) }
 func (ab *slicePair[T]) LenB() int             { return len(ab.b) }
 func (ab *slicePair[T]) Equal(ai, bi int) bool { return ab.a[ai] == ab.b[bi] }
 
+func (ab *slicePair[T]) WriteATo(w io.Writer, i int) (int, error) { return fmt.Fprint(w, ab.a[i]) }
+func (ab *slicePair[T]) WriteBTo(w io.Writer, i int) (int, error) { return fmt.Fprint(w, ab.b[i]) }
+
+// visibleLines is a pair of slices of lines of text
+// that writes each line with invisible characters escaped.
+type visibleLines struct{ slicePair[string] }
+
+func (ab *visibleLines) WriteATo(w io.Writer, i int) (int, error) { return io.WriteString(w, visible(ab.a[i])) }
+func (ab *visibleLines) WriteBTo(w io.Writer, i int) (int, error) { return io.WriteString(w, visible(ab.b[i])) }
+
+// visible returns line s with characters that are hard to see
+// in a terminal escaped in Go syntax: control characters
+// other than tab, invalid UTF-8, and non-printing and space
+// characters other than ASCII space, such as U+00A0 NO-BREAK
+// SPACE and U+200B ZERO WIDTH SPACE. Trailing spaces and tabs
+// are also escaped, as \x20 and \t.
+func visible(s string) string {
+	body := strings.TrimRight(s, " \t")
+	var b strings.Builder
+	for i, r := range body {
+		switch {
+		case r == utf8.RuneError && !strings.HasPrefix(body[i:], "\uFFFD"):
+			fmt.Fprintf(&b, `\x%02x`, body[i])
+		case r == ' ' || r == '\t' || unicode.IsPrint(r):
+			b.WriteRune(r)
+		case r < utf8.RuneSelf:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case r <= 0xffff:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			fmt.Fprintf(&b, `\U%08x`, r)
+		}
+	}
+	for _, c := range s[len(body):] {
+		if c == ' ' {
+			b.WriteString(`\x20`)
+		} else {
+			b.WriteString(`\t`)
+		}
+	}
+	return b.String()
+}
+
 func accum(a []string) (is []int) {
 	n, is := 0, append(is, 0)
 	for _, sub := range a {
@@ -88,6 +285,32 @@ func accum(a []string) (is []int) {
 	return is
 }
 
+// splitLines splits s into lines the same way as bufio.ScanLines,
+// dropping line terminators.
+func splitLines(s string) (a []string) {
+	scan := bufio.NewScanner(strings.NewReader(s))
+	scan.Buffer(nil, len(s)+1)
+	for scan.Scan() {
+		a = append(a, scan.Text())
+	}
+	return a
+}
+
+// equalLines reports whether a and b have the same lines,
+// ignoring line terminators.
+func equalLines(a, b string) bool {
+	as, bs := splitLines(a), splitLines(b)
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func splitRunes(s string) (a []string) {
 	for s != "" {
 		r, n := utf8.DecodeRuneInString(s)
@@ -1,6 +1,7 @@
 package diff_test
 
 import (
+	"strings"
 	"testing"
 
 	"kr.dev/diff"
@@ -153,3 +154,131 @@ string[14:15]: "a" != "o"
 string[27:27]: "" != "="
 `
 )
+
+func TestTextContext(t *testing.T) {
+	a := "a\nb\nc\nd\ne\n"
+	b := "a\nb\nC\nd\ne\n"
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.TextContext(1))
+	want := "--- a\n" +
+		"+++ b\n" +
+		"@@ -2,3 +2,3 @@\n" +
+		" b\n" +
+		"-c\n" +
+		"+C\n" +
+		" d\n" +
+		"\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestStrings(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", ""},
+		{"a\nb\nc\n", "a\nB\nc\n", "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n\n"},
+		{"x", "y", "--- a\n+++ b\n@@ -1,1 +0,0 @@\n-x\n@@ -0,0 +1,1 @@\n+y\n\n"},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Strings(gotp.Printf, tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("Strings(%q, %q):", tt.a, tt.b)
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	cases := []struct {
+		a, b []byte
+		want string
+	}{
+		{[]byte("a\nb\n"), []byte("a\nb\n"), ""},
+		{[]byte("a\nb\n"), []byte("a\nc\n"), "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n\n"},
+		{
+			[]byte("\x00\x01\x02\x03"),
+			[]byte("\x00\x01\x02\x04"),
+			"--- a\n+++ b\n@@ -1,1 +0,0 @@\n" +
+				"-00000000  00 01 02 03                                       |....|\n" +
+				"@@ -0,0 +1,1 @@\n" +
+				"+00000000  00 01 02 04                                       |....|\n\n",
+		},
+	}
+	for _, tt := range cases {
+		var got string
+		gotp := (*stringPrinter)(&got)
+		diff.Bytes(gotp.Printf, tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("Bytes(%q, %q):", tt.a, tt.b)
+			t.Logf("got:\n%s", got)
+			t.Logf("want:\n%s", tt.want)
+		}
+	}
+}
+
+func TestElideCommon(t *testing.T) {
+	p := strings.Repeat("x", 100)
+	type T struct{ S string }
+
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, T{p + "a" + p}, T{p + "b" + p}, diff.EmitFull)
+	want := "diff_test.T:\n" +
+		"a.S:\n" + tab + "…[84 bytes equal]…\"xxxxxxxxxxxxxxxxaxxxxxxxxxxxxxxxx\"…[84 bytes equal]…\n" +
+		"b.S:\n" + tab + "…[84 bytes equal]…\"xxxxxxxxxxxxxxxxbxxxxxxxxxxxxxxxx\"…[84 bytes equal]…\n"
+	if got != want {
+		t.Errorf("bad full diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, []byte(p+"a\"b"+p), []byte(p+"c\"d"+p), diff.EmitFull)
+	want = "a:\n" + tab + "…[84 bytes equal]…\"xxxxxxxxxxxxxxxxa\\\"bxxxxxxxxxxxxxxxx\"…[84 bytes equal]…\n" +
+		"b:\n" + tab + "…[84 bytes equal]…\"xxxxxxxxxxxxxxxxc\\\"dxxxxxxxxxxxxxxxx\"…[84 bytes equal]…\n"
+	if got != want {
+		t.Errorf("bad full []byte diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, p+"\xff", p+"\xfe")
+	want = "binary: …[84 bytes equal]…\"xxxxxxxxxxxxxxxx\\xff\" != …[84 bytes equal]…\"xxxxxxxxxxxxxxxx\\xfe\"\n"
+	if got != want {
+		t.Errorf("bad binary diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, T{"a"}, T{"b"}, diff.EmitFull)
+	if strings.Contains(got, "equal]") {
+		t.Errorf("short strings elided: %q", got)
+	}
+}
+
+func TestTextInvisible(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\n"
+	b := "one\ntwo\u00a0\nthree \nfo\u200bur\n"
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b)
+	want := "--- a\n+++ b\n@@ -1,4 +1,4 @@\n one\n" +
+		"-two\n-three\n-four\n" +
+		"+two\\u00a0\n+three\\x20\n+fo\\u200bur\n\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
@@ -0,0 +1,68 @@
+package diff
+
+import (
+	"io"
+	"strings"
+
+	"kr.dev/diff/internal/indent"
+)
+
+// A treeNode accumulates output for EmitTree.
+// Each node is one step of a path.
+type treeNode struct {
+	label    string
+	msgs     []string
+	children []*treeNode
+	index    map[string]*treeNode
+}
+
+// add adds msg to the node found by following labels from n.
+func (n *treeNode) add(labels []string, msg string) {
+	for _, label := range labels {
+		c := n.index[label]
+		if c == nil {
+			c = &treeNode{label: label}
+			if n.index == nil {
+				n.index = map[string]*treeNode{}
+			}
+			n.index[label] = c
+			n.children = append(n.children, c)
+		}
+		n = c
+	}
+	n.msgs = append(n.msgs, strings.TrimSuffix(msg, "\n"))
+}
+
+// writeTo writes the tree rooted at n to w.
+// Chains of nodes with one child and no messages
+// are joined onto a single line.
+func (n *treeNode) writeTo(w io.Writer) {
+	label := n.label
+	for len(n.msgs) == 0 && len(n.children) == 1 {
+		n = n.children[0]
+		label += n.label
+	}
+	switch {
+	case len(n.msgs) == 1 && label == "":
+		io.WriteString(w, n.msgs[0]+"\n")
+	case len(n.msgs) == 1:
+		io.WriteString(w, label+": "+n.msgs[0]+"\n")
+	case len(n.msgs) == 0:
+		io.WriteString(w, label+"\n")
+	default:
+		if label != "" {
+			io.WriteString(w, label+":\n")
+			w = indent.New(w, tab)
+		}
+		for _, msg := range n.msgs {
+			io.WriteString(w, msg+"\n")
+		}
+	}
+	ww := indent.New(w, tab)
+	if label == "" {
+		ww = w
+	}
+	for _, c := range n.children {
+		c.writeTo(ww)
+	}
+}
@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"reflect"
+	"sort"
+)
+
+// walkUnordered compares slices av and bv of type t
+// without regard to the order of their elements.
+//
+// First it pairs up equal elements. Then it pairs up
+// the remaining elements greedily, closest first,
+// as measured by Distance, and walks each pair.
+// Any elements left over are reported as
+// removed from av or added to bv.
+func (d *differ) walkUnordered(e emitfer, t reflect.Type, av, bv reflect.Value) {
+	d.config.helper()
+	na, nb := av.Len(), bv.Len()
+	aPair := make([]int, na) // index in bv, or -1
+	bPair := make([]int, nb) // index in av, or -1
+	for i := range aPair {
+		aPair[i] = -1
+	}
+	for j := range bPair {
+		bPair[j] = -1
+	}
+
+	for i := 0; i < na; i++ {
+		for j := 0; j < nb; j++ {
+			if bPair[j] < 0 && d.equalAt(indexStep(i), av.Index(i), bv.Index(j)) {
+				aPair[i], bPair[j] = j, i
+				break
+			}
+		}
+	}
+
+	type candidate struct{ i, j, dist int }
+	var cands []candidate
+	for i := 0; i < na; i++ {
+		if aPair[i] >= 0 {
+			continue
+		}
+		for j := 0; j < nb; j++ {
+			if bPair[j] >= 0 {
+				continue
+			}
+			ce := d.newCounter(appendStep(d.path, indexStep(i)), true)
+			d.fork().walk(ce, av.Index(i), bv.Index(j), true, false)
+			cands = append(cands, candidate{i, j, ce.n})
+		}
+	}
+	sort.SliceStable(cands, func(x, y int) bool {
+		return cands[x].dist < cands[y].dist
+	})
+	for _, c := range cands {
+		if aPair[c.i] < 0 && bPair[c.j] < 0 {
+			aPair[c.i], bPair[c.j] = c.j, c.i
+		}
+	}
+
+	for i, j := range aPair {
+		switch {
+		case j < 0:
+			e.sub(t, indexStep(i)).emitf(av.Index(i), reflect.Value{}, "(removed)")
+		case i == j:
+			d.walk(e.sub(t, indexStep(i)), av.Index(i), bv.Index(j), true, false)
+		default:
+			d.walk(e.sub(t, pairStep(i, j)), av.Index(i), bv.Index(j), true, false)
+		}
+	}
+	for j, i := range bPair {
+		if i < 0 {
+			bj := bv.Index(j)
+			e.sub(t, indexStep(j)).emitf(reflect.Value{}, bj, "(added) %v", formatShort(&d.config.display, bj, false))
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package diff
+
+import (
+	"math"
+	"reflect"
+)
+
+// A vectorTol compares float vectors by an aggregate
+// metric, for VectorMaxAbs and VectorRMSE.
+type vectorTol struct {
+	name string  // the metric, such as "RMSE", in output
+	tol  float64 // largest metric for equal vectors
+	rmse bool    // root mean square error; else max abs
+}
+
+// isFloatVector reports whether t is a slice
+// or array of floats.
+func isFloatVector(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		k := t.Elem().Kind()
+		return k == reflect.Float32 || k == reflect.Float64
+	}
+	return false
+}
+
+// measure returns the metric for float vectors av
+// and bv, of the same length, and the index of the
+// element pair that differs most.
+// The metric is NaN if any pair includes a NaN.
+func (vt *vectorTol) measure(av, bv reflect.Value) (m float64, worst int) {
+	worstDiff := -1.0
+	for i := 0; i < av.Len(); i++ {
+		x := math.Abs(av.Index(i).Float() - bv.Index(i).Float())
+		if math.IsNaN(x) {
+			return math.NaN(), i
+		}
+		if x > worstDiff {
+			worstDiff, worst = x, i
+		}
+		if vt.rmse {
+			m += x * x
+		} else if x > m {
+			m = x
+		}
+	}
+	if vt.rmse && av.Len() > 0 {
+		m = math.Sqrt(m / float64(av.Len()))
+	}
+	return m, worst
+}
+
+// walkVector compares float vectors av and bv, of the same
+// length, by vt's metric, emitting one difference with the
+// metric if it is over the tolerance.
+func (d *differ) walkVector(e emitfer, av, bv reflect.Value, vt *vectorTol) {
+	d.config.helper()
+	m, worst := vt.measure(av, bv)
+	if m <= vt.tol {
+		d.tracef(e, "%s %g, equal (%s)", vt.name, m, vt.option())
+		return
+	}
+	e.emitf(av, bv, "%s %g > %g (worst at [%d]: %v != %v)", vt.name, m, vt.tol,
+		worst, formatShort(&d.config.display, av.Index(worst), false), formatShort(&d.config.display, bv.Index(worst), false))
+}
+
+func (vt *vectorTol) option() string {
+	if vt.rmse {
+		return "VectorRMSE"
+	}
+	return "VectorMaxAbs"
+}
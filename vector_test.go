@@ -0,0 +1,59 @@
+package diff_test
+
+import (
+	"math"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestVector(t *testing.T) {
+	type T struct {
+		V []float64
+		A [2]float32
+	}
+	a := T{[]float64{1, 2, 3, 1}, [2]float32{1, 2}}
+	cases := []struct {
+		name string
+		b    T
+		opt  diff.Option
+		want string
+	}{
+		{"maxabs", T{[]float64{1.005, 2, 2.995, 1}, [2]float32{1.005, 2}}, diff.VectorMaxAbs(0.01), ""},
+		{"maxabs-over", T{[]float64{1, 2, 3.5, 1}, a.A}, diff.VectorMaxAbs(0.01),
+			"diff_test.T.V: max abs 0.5 > 0.01 (worst at [2]: 3 != 3.5)\n"},
+		{"rmse", T{[]float64{1, 2, 3.1, 1}, a.A}, diff.VectorRMSE(0.06), ""},
+		{"rmse-over", T{[]float64{1, 2, 3.5, 1}, a.A}, diff.VectorRMSE(0.05),
+			"diff_test.T.V: RMSE 0.25 > 0.05 (worst at [2]: 3 != 3.5)\n"},
+		{"array", T{a.V, [2]float32{1, 3}}, diff.VectorMaxAbs(0.5),
+			"diff_test.T.A: max abs 1 > 0.5 (worst at [1]: 2 != 3)\n"},
+		{"nan", T{[]float64{1, math.NaN(), 3, 1}, a.A}, diff.VectorMaxAbs(1),
+			"diff_test.T.V: max abs NaN > 1 (worst at [1]: 2 != NaN)\n"},
+		{"length", T{[]float64{1, 2, 3}, a.A}, diff.VectorMaxAbs(1),
+			"diff_test.T.V: {len 4} != {len 3}\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			diff.Each((*stringPrinter)(&got).Printf, a, tt.b, tt.opt)
+			if got != tt.want {
+				t.Errorf("bad diff")
+				t.Logf("got:\n%s", got)
+				t.Logf("want:\n%s", tt.want)
+			}
+		})
+	}
+}
+
+func TestVectorBad(t *testing.T) {
+	for _, tol := range []float64{-1, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("VectorMaxAbs(%v) didn't panic", tol)
+				}
+			}()
+			diff.VectorMaxAbs(tol)
+		}()
+	}
+}
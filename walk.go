@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Walk traverses v depth first, calling fn for v and for
+// each value reachable from it: struct fields, array and slice
+// elements, map entries, and the values held in pointers and
+// interfaces. Pointers and interfaces are visited at the same
+// path as the value they hold. If fn returns false, Walk
+// doesn't traverse the values inside that value.
+//
+// Walk visits each pointer, map, and slice at most once,
+// so it terminates on cyclic data.
+// Struct fields are visited even if unexported,
+// except those excluded by a diff struct tag of "-".
+// Map entries are visited in sorted key order.
+//
+// Values reached through a pointer in v can be modified
+// by fn, as long as they are settable; v itself is
+// copied, so modifying it has no effect on the caller.
+//
+// Options control the syntax of the paths (see PathJQ,
+// for example); other options have no effect.
+func Walk(v any, fn func(path Path, v reflect.Value) bool, opt ...Option) {
+	c := newConfig(opt...)
+	rv := rootValue(v)
+	if !rv.IsValid() {
+		return
+	}
+	w := &walker{
+		fn:    fn,
+		style: c.pathStyle,
+		root:  rv.Type(),
+		seen:  map[visit]bool{},
+	}
+	w.walk(nil, rv)
+}
+
+type walker struct {
+	fn    func(Path, reflect.Value) bool
+	style pathStyle
+	root  reflect.Type
+	seen  map[visit]bool
+}
+
+func (w *walker) walk(path []step, v reflect.Value) {
+	if !w.fn(Path{root: w.root, steps: path, style: w.style}, v) {
+		return
+	}
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		vis := visit{unsafe.Pointer(v.Pointer()), t}
+		if w.seen[vis] {
+			return
+		}
+		w.seen[vis] = true
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			w.walk(appendStep(path, indexStep(i)), v.Index(i))
+		}
+	case reflect.Struct:
+		if !v.CanAddr() {
+			v = addressable(v)
+		}
+		for _, f := range structPlan(t) {
+			w.walk(appendStep(path, f.step), access(v.Field(f.index)))
+		}
+	case reflect.Map:
+		for _, k := range sortedKeys(v) {
+			w.walk(appendStep(path, keyStep(k)), v.MapIndex(k))
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			w.walk(path, v.Elem())
+		}
+	}
+}
+
+// appendStep returns a new path made of path followed by s.
+// It doesn't modify path, which may be shared.
+func appendStep(path []step, s step) []step {
+	p := make([]step, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, s)
+}
@@ -0,0 +1,66 @@
+package diff_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestWalk(t *testing.T) {
+	type Node struct {
+		Name   string
+		Tags   map[string]int
+		Next   *Node
+		secret string `diff:"-"`
+	}
+	n := &Node{Name: "a", Tags: map[string]int{"x": 1}, secret: "s"}
+	n.Next = n // cycle
+
+	var got []string
+	diff.Walk(n, func(p diff.Path, v reflect.Value) bool {
+		got = append(got, fmt.Sprintf("%s %v", p, v.Kind()))
+		return true
+	}, diff.PathJQ)
+	want := []string{
+		". ptr",
+		". struct",
+		".Name string",
+		".Tags map",
+		".Tags.x int",
+		".Next ptr",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk visited:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWalkSkipAndModify(t *testing.T) {
+	type User struct {
+		Name     string
+		Password string
+		Friends  []User
+	}
+	u := &User{Name: "a", Password: "p", Friends: []User{{Name: "b", Password: "q"}}}
+	var paths []string
+	diff.Walk(u, func(p diff.Path, v reflect.Value) bool {
+		paths = append(paths, p.String())
+		if strings.HasSuffix(p.String(), ".Password") {
+			v.SetString("REDACTED")
+		}
+		return !strings.HasSuffix(p.String(), ".Friends[0]")
+	})
+	if u.Password != "REDACTED" {
+		t.Errorf("Password = %q, want REDACTED", u.Password)
+	}
+	if u.Friends[0].Password != "q" {
+		t.Errorf("Friends[0].Password = %q, want unchanged", u.Friends[0].Password)
+	}
+	want := []string{"*diff_test.User", "*diff_test.User", "*diff_test.User.Name", "*diff_test.User.Password",
+		"*diff_test.User.Friends", "*diff_test.User.Friends[0]"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %q, want %q", paths, want)
+	}
+}
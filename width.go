@@ -0,0 +1,90 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// minOperand is the fewest runes Width shortens
+// either side of a difference to.
+const minOperand = 16
+
+// envWidth returns the terminal width given by
+// the COLUMNS environment variable, or 80 if it
+// is not set to a positive number.
+func envWidth() int {
+	n, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err != nil || n <= 0 {
+		return 80
+	}
+	return n
+}
+
+// fitOperands shortens x and y evenly, so that the line
+// prefix followed by "x != y" is at most w runes long
+// where possible.
+func fitOperands(prefix, x, y string, w int) (string, string) {
+	budget := w - utf8.RuneCountInString(prefix+" != ")
+	if utf8.RuneCountInString(x+y) <= budget {
+		return x, y
+	}
+	n := budget / 2
+	if n < minOperand {
+		n = minOperand
+	}
+	return abbrev(x, n), abbrev(y, n)
+}
+
+// fitLines shortens each line of s at its end
+// to at most w runes, ending it with an ellipsis.
+func fitLines(s string, w int) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if utf8.RuneCountInString(line) > w {
+			r := []rune(line)
+			lines[i] = string(r[:w-1]) + "…"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// abbrev shortens s to n runes, if it is longer,
+// by replacing its middle with an ellipsis.
+func abbrev(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	head := (n - 1) / 2
+	tail := n - 1 - head
+	return string(r[:head]) + "…" + string(r[len(r)-tail:])
+}
+
+// A clippedFull writes a value formatted by formatFull
+// with each line cut short at n runes, for FullWidth.
+type clippedFull struct {
+	f fmt.Formatter
+	n int
+}
+
+func (c clippedFull) Format(f fmt.State, verb rune) {
+	lines := strings.Split(fmt.Sprintf("%#v", c.f), "\n")
+	for i, line := range lines {
+		lines[i] = clipLine(line, c.n)
+	}
+	io.WriteString(f, strings.Join(lines, "\n"))
+}
+
+// clipLine shortens line to its first n runes, if it is
+// longer, followed by a marker saying how many were left out.
+func clipLine(line string, n int) string {
+	if utf8.RuneCountInString(line) <= n {
+		return line
+	}
+	r := []rune(line)
+	return fmt.Sprintf("%s…[%d more]", string(r[:n]), len(r)-n)
+}
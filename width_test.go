@@ -0,0 +1,67 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"kr.dev/diff"
+)
+
+func TestWidth(t *testing.T) {
+	type T struct{ Body string }
+	a := T{strings.Repeat("ab", 50)}
+	b := T{strings.Repeat("cd", 50)}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, a, b, diff.Width(70))
+	want := "diff_test.T.Body[0:100]: \"abababab\u2026babababab\" != \"cdcdcdcd\u2026dcdcdcdcd\"\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	t.Setenv("COLUMNS", "40")
+	got = ""
+	diff.Each(gotp.Printf, a, b, diff.Width(-1))
+	want = ""
+	wantp := (*stringPrinter)(&want)
+	diff.Each(wantp.Printf, a, b, diff.Width(40))
+	if got != want {
+		t.Errorf("Width(-1) = %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, map[string]string{}, map[string]string{"k": a.Body}, diff.Width(40))
+	want = "map[string]string[\"k\"]: (added) \"ababab\u2026\n"
+	if got != want {
+		t.Errorf("added: got %q, want %q", got, want)
+	}
+
+	got = ""
+	diff.Each(gotp.Printf, a, b)
+	if !strings.Contains(got, strings.Repeat("ab", 50)) {
+		t.Errorf("default: got %q, want the whole value", got)
+	}
+}
+
+func TestFullWidth(t *testing.T) {
+	type T struct {
+		N    int
+		Body string
+	}
+	a := T{1, strings.Repeat("a", 1000)}
+	var got string
+	diff.Each((*stringPrinter)(&got).Printf, a, &a, diff.EmitFull, diff.FullWidth(20))
+	want := "a:\n" +
+		tab + "diff_test.T{\n" +
+		tab + tab + "N:    1,\n" +
+		tab + tab + "Body: \"aaaaa…[997 more]\n" +
+		tab + "}\n" +
+		"b:\n" +
+		tab + "&diff_test.T{\n" +
+		tab + tab + "N:    1,\n" +
+		tab + tab + "Body: \"aaaaa…[997 more]\n" +
+		tab + "}\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// lookupFunc returns the entry in m for values of type t:
+// the func registered for t itself, if any, or else
+// the func registered for an interface type that t
+// implements. If t implements more than one such
+// interface, it uses the first in order of type string,
+// so the choice doesn't depend on map order.
+func lookupFunc[V any](m map[reflect.Type]V, t reflect.Type) (V, bool) {
+	if f, ok := m[t]; ok {
+		return f, true
+	}
+	var match []reflect.Type
+	for it := range m {
+		if it.Kind() == reflect.Interface && t.Implements(it) {
+			match = append(match, it)
+		}
+	}
+	if len(match) == 0 {
+		var zero V
+		return zero, false
+	}
+	sort.Slice(match, func(i, j int) bool {
+		return match[i].String() < match[j].String()
+	})
+	return m[match[0]], true
+}
+
+// A funcCache holds the results of lookupFunc for the
+// transforms and format funcs of a config, by type,
+// so each type is looked up once.
+// A config gets a new one whenever its maps are changed.
+type funcCache struct {
+	xform  sync.Map // reflect.Type to funcEntry[[]transform]
+	format sync.Map // reflect.Type to funcEntry[reflect.Value]
+}
+
+type funcEntry[V any] struct {
+	f  V
+	ok bool
+}
+
+// cachedLookup is like lookupFunc, but it saves
+// the result for t in cache.
+func cachedLookup[V any](cache *sync.Map, m map[reflect.Type]V, t reflect.Type) (V, bool) {
+	if len(m) == 0 {
+		var zero V
+		return zero, false
+	}
+	if e, ok := cache.Load(t); ok {
+		e := e.(funcEntry[V])
+		return e.f, e.ok
+	}
+	f, ok := lookupFunc(m, t)
+	cache.Store(t, funcEntry[V]{f, ok})
+	return f, ok
+}
+
+// xformFunc returns the transforms in c
+// for values of type t. See lookupFunc.
+func (c *config) xformFunc(t reflect.Type) ([]transform, bool) {
+	return cachedLookup(&c.funcs.xform, c.xform, t)
+}
+
+// formatFunc returns the format func in c
+// for values of type t. See lookupFunc.
+func (c *config) formatFunc(t reflect.Type) (reflect.Value, bool) {
+	return cachedLookup(&c.funcs.format, c.format, t)
+}
+
+// A transform is a func given to Transform, wrapped
+// to take its argument as an any, so it can be called
+// without reflect.Value.Call.
+type transform struct {
+	in reflect.Type // the parameter type, T
+	f  func(any) any
+}
+
+// applyChain applies the transforms in chain to v, in order,
+// each to the result of the one before, and returns the result.
+// It stops early if a result can't be passed to the next
+// transform in the chain.
+func applyChain(chain []transform, v reflect.Value) reflect.Value {
+	for _, x := range chain {
+		if !v.IsValid() || !v.Type().AssignableTo(x.in) {
+			break
+		}
+		v = reflect.ValueOf(x.f(v.Interface()))
+	}
+	return addressable(v)
+}
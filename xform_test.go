@@ -1,6 +1,10 @@
 package diff_test
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,3 +72,108 @@ func TestNoTimeLoc(t *testing.T) {
 		t.Fail()
 	}
 }
+
+type celsius float64
+
+func (c celsius) String() string { return fmt.Sprintf("%.0f\u00b0C", float64(c)) }
+
+type kelvin float64
+
+func (k kelvin) String() string { return fmt.Sprintf("%.1fK", float64(k)) }
+
+func TestTransformInterface(t *testing.T) {
+	type T struct {
+		Err  error
+		Temp celsius
+		K    kelvin
+	}
+	opt := []diff.Option{
+		diff.Transform(func(err error) any { return err.Error() }),
+		diff.Transform(func(s fmt.Stringer) any { return s.String() }),
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf,
+		T{errors.New("x"), 20.1, 3},
+		T{fmt.Errorf("x"), 20.2, 3.04},
+		opt...)
+	if got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+
+	opt = append(opt, diff.Transform(func(k kelvin) any { return float64(k) }))
+	got = ""
+	diff.Each(gotp.Printf, T{Err: io.EOF, K: 3}, T{Err: io.EOF, K: 3.1}, opt...)
+	if want := "diff_test.T.K: 3.0K != 3.1K\n"; got != want {
+		t.Errorf("got %q, want %q (exact type should take precedence)", got, want)
+	}
+}
+
+func TestFormatInterface(t *testing.T) {
+	type T struct {
+		Temp celsius
+		K    kelvin
+		N    int
+	}
+	opt := []diff.Option{
+		diff.Format(func(a, b fmt.Stringer) string {
+			return a.String() + " -> " + b.String()
+		}),
+		diff.Format(func(a, b kelvin) string { return "kelvin changed" }),
+	}
+	var got string
+	gotp := (*stringPrinter)(&got)
+	diff.Each(gotp.Printf, T{20, 3, 1}, T{21, 4, 2}, opt...)
+	want := "diff_test.T.Temp: 20\u00b0C -> 21\u00b0C\n" +
+		"diff_test.T.K: kelvin changed\n" +
+		"diff_test.T.N: 1 != 2\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTransformChain(t *testing.T) {
+	type T struct{ S string }
+	trim := diff.Transform(func(s string) any { return strings.TrimSpace(s) })
+	lower := diff.Transform(func(s string) any { return strings.ToLower(s) })
+	length := diff.Transform(func(s string) any { return len(s) })
+
+	cases := []struct {
+		name string
+		a, b T
+		opt  []diff.Option
+		want string
+	}{
+		{"chain", T{" ABC "}, T{"abc"}, []diff.Option{trim, lower}, ""},
+		{"first only", T{" ABC "}, T{"abc"}, []diff.Option{trim}, "diff_test.T.S: \" ABC \" != \"abc\"\n"},
+		{"stops at other type", T{"ab"}, T{"AB"}, []diff.Option{length, lower}, ""},
+		{"removed", T{" ABC "}, T{"abc"}, []diff.Option{trim, lower, diff.TransformRemove[string]()},
+			"diff_test.T.S: \" ABC \" != \"abc\"\n"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			gotp := (*stringPrinter)(&got)
+			diff.Each(gotp.Printf, tt.a, tt.b, tt.opt...)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkTransform(b *testing.B) {
+	f := func(string, ...any) (int, error) { return 0, nil }
+	x := make([]kelvin, 10000)
+	y := make([]kelvin, 10000)
+	for i := range x {
+		x[i] = kelvin(i)
+		y[i] = kelvin(i)
+	}
+	opt := diff.Transform(func(k kelvin) any { return float64(k) })
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		diff.Each(f, x, y, opt)
+	}
+}
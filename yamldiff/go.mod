@@ -0,0 +1,15 @@
+module kr.dev/diff/yamldiff
+
+go 1.18
+
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	kr.dev/diff v0.0.0
+)
+
+require (
+	github.com/pkg/diff v0.0.0-20210226163009-20ebb0f2a09e // indirect
+	github.com/rogpeppe/go-internal v1.8.1 // indirect
+)
+
+replace kr.dev/diff => ../
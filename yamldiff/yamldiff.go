@@ -0,0 +1,32 @@
+// Package yamldiff compares YAML documents.
+package yamldiff
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+	"kr.dev/diff"
+)
+
+// Each parses a and b as YAML documents and compares them,
+// calling f for each difference it finds.
+// The comparison is semantic: mapping key order, comments,
+// and formatting don't matter, and anchors and aliases
+// are resolved before comparing.
+// Paths are written in jq syntax (see diff.PathJQ),
+// unless opt selects a different syntax.
+//
+// Only the first document in each of a and b is compared.
+// Each returns an error, without calling f, if either
+// a or b is not valid YAML.
+func Each(f func(format string, arg ...any) (int, error), a, b []byte, opt ...diff.Option) error {
+	var av, bv any
+	if err := yaml.Unmarshal(a, &av); err != nil {
+		return fmt.Errorf("yamldiff: parsing a: %w", err)
+	}
+	if err := yaml.Unmarshal(b, &bv); err != nil {
+		return fmt.Errorf("yamldiff: parsing b: %w", err)
+	}
+	diff.Each(f, av, bv, diff.PathJQ, diff.OptionList(opt...))
+	return nil
+}
@@ -0,0 +1,53 @@
+package yamldiff_test
+
+import (
+	"fmt"
+	"testing"
+
+	"kr.dev/diff/yamldiff"
+)
+
+func TestEach(t *testing.T) {
+	a := `
+defaults: &defaults
+  image: app:1
+  replicas: 2
+web:
+  <<: *defaults
+  port: 80
+`
+	b := `
+web:
+  port: 80
+  replicas: 3
+  image: app:1 # pinned
+`
+	var got string
+	f := func(format string, arg ...any) (int, error) {
+		s := fmt.Sprintf(format, arg...)
+		got += s
+		return len(s), nil
+	}
+	err := yamldiff.Each(f, []byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ".defaults: (removed)\n" +
+		".web.replicas: int(2) != int(3)\n"
+	if got != want {
+		t.Errorf("bad diff")
+		t.Logf("got:\n%s", got)
+		t.Logf("want:\n%s", want)
+	}
+}
+
+func TestEachError(t *testing.T) {
+	f := func(format string, arg ...any) (int, error) {
+		t.Errorf("unexpected call to f")
+		return 0, nil
+	}
+	err := yamldiff.Each(f, []byte("a: [1"), []byte("a: 1"))
+	if err == nil {
+		t.Errorf("Each(invalid) = nil, want error")
+	}
+}